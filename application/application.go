@@ -5,9 +5,21 @@ import "github.com/spf13/cobra"
 func init() {
 	Cmd.AddCommand(cmdApplicationList)
 	Cmd.AddCommand(cmdApplicationShow)
+	Cmd.AddCommand(cmdApplicationDiff)
+	Cmd.AddCommand(promoteCmd())
+	Cmd.AddCommand(cloneCmd())
+	Cmd.AddCommand(backupCmd())
+	Cmd.AddCommand(restoreCmd())
+	Cmd.AddCommand(startCmd())
+	Cmd.AddCommand(stopCmd())
+	Cmd.AddCommand(restartCmd())
+	Cmd.AddCommand(graphCmd())
+	Cmd.AddCommand(eventsCmd())
+	Cmd.AddCommand(logsCmd())
 
 	cmdApplicationDomain.AddCommand(cmdApplicationDomainList)
 	cmdApplicationDomain.AddCommand(cmdApplicationDomainDetach)
+	cmdApplicationDomainDetach.Flags().BoolVar(&domainDetachYes, "yes", false, "do not ask for confirmation")
 
 	Cmd.AddCommand(cmdApplicationDomain)
 
@@ -20,6 +32,9 @@ func init() {
 	cmdApplicationEnv.AddCommand(cmdApplicationListEnv)
 	cmdApplicationEnv.AddCommand(cmdApplicationSetEnv)
 	cmdApplicationEnv.AddCommand(cmdApplicationDelEnv)
+	cmdApplicationEnv.AddCommand(cmdApplicationImportEnv)
+	cmdApplicationEnv.AddCommand(cmdApplicationExportEnv)
+	cmdApplicationEnv.AddCommand(envSyncCmd())
 
 	Cmd.AddCommand(cmdApplicationEnv)
 }
@@ -0,0 +1,315 @@
+package application
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/network"
+	"github.com/runabove/sail/service"
+	"github.com/spf13/cobra"
+)
+
+// backupVersion is the version of the archive layout. Bump it on incompatible changes.
+const backupVersion = 1
+
+var backupOutput string
+
+func backupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Save the configuration of an application to an archive: sail application backup [<applicationName>] [-o <file.tar.gz>]",
+		Long: `Save the configuration of an application to an archive: sail application backup [<applicationName>] [-o <file.tar.gz>]
+
+The archive is a gzipped tarball of JSON documents:
+	manifest.json          archive version, application name and date
+	env.json               application environment variables
+	networks.json          private networks with their subnet and ranges
+	routes.json            HTTP routes attached to services
+	webhooks.json          application webhooks
+	repositories.json      repositories of the application
+	services/<name>.json   service definitions
+
+Container images are not saved. Use 'sail application restore' to recreate the application.
+	"example: sail application backup myapp -o myapp.tar.gz"
+`,
+		Run: cmdBackup,
+	}
+
+	cmd.Flags().StringVarP(&backupOutput, "output", "o", "", "archive file, default to <applicationName>-<date>.tar.gz")
+
+	return cmd
+}
+
+// backupManifest describes an archive
+type backupManifest struct {
+	Version     int    `json:"version"`
+	Application string `json:"application"`
+	Date        string `json:"date"`
+}
+
+type backupNetwork struct {
+	Name   string   `json:"name"`
+	Subnet string   `json:"subnet"`
+	Ranges []string `json:"ranges"`
+}
+
+type backupRoute struct {
+	Service string `json:"service"`
+	Domain  string `json:"domain"`
+	Pattern string `json:"pattern"`
+	Method  string `json:"method"`
+}
+
+type backupRepository struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Source string `json:"source,omitempty"`
+}
+
+// appBackup holds every document of an archive
+type appBackup struct {
+	Manifest     backupManifest
+	Env          map[string]string
+	Networks     []backupNetwork
+	Routes       []backupRoute
+	Webhooks     []string
+	Repositories []backupRepository
+	Services     []service.ServiceSpec
+}
+
+func cmdBackup(cmd *cobra.Command, args []string) {
+	var app string
+	switch len(args) {
+	case 0:
+		app = internal.GetUserName()
+	case 1:
+		app = args[0]
+	default:
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail application backup [<applicationName>]. Please see sail application backup --help")
+		os.Exit(1)
+	}
+	internal.Check(internal.CheckName(app))
+
+	now := time.Now().UTC()
+	if backupOutput == "" {
+		backupOutput = fmt.Sprintf("%s-%s.tar.gz", app, now.Format("20060102-150405"))
+	}
+
+	backup := fetchBackup(app)
+	backup.Manifest.Date = now.Format(time.RFC3339)
+
+	internal.Check(writeBackup(backupOutput, backup))
+
+	fmt.Fprintf(os.Stderr, "Saved %d service(s), %d env var(s), %d network(s), %d route(s), %d webhook(s) and %d repository(ies) of %s to %s\n",
+		len(backup.Services), len(backup.Env), len(backup.Networks), len(backup.Routes), len(backup.Webhooks), len(backup.Repositories), app, backupOutput)
+}
+
+// fetchBackup reads the whole configuration of an application
+func fetchBackup(app string) appBackup {
+	backup := appBackup{
+		Manifest: backupManifest{Version: backupVersion, Application: app},
+		Env:      envList(app),
+	}
+
+	for _, name := range network.List(app) {
+		subnet, ranges := network.Get(app, name)
+		backup.Networks = append(backup.Networks, backupNetwork{Name: name, Subnet: subnet, Ranges: ranges})
+	}
+
+	backup.Routes = fetchRoutes(app)
+	backup.Webhooks = fetchWebhooks(app)
+	backup.Repositories = fetchRepositories(app)
+
+	for _, name := range service.ListServices(app) {
+		backup.Services = append(backup.Services, service.FetchServiceSpec(app, name))
+	}
+
+	return backup
+}
+
+// fetchRoutes returns the routes attached to the services of an application, sorted
+func fetchRoutes(app string) []backupRoute {
+	var domains map[string][]backupRoute
+	var routes []backupRoute
+
+	b := internal.GetWantJSON(fmt.Sprintf("/applications/%s/attached-domains", app))
+	internal.Check(json.Unmarshal(b, &domains))
+
+	for domain, attached := range domains {
+		for _, route := range attached {
+			route.Domain = domain
+			routes = append(routes, route)
+		}
+	}
+
+	sort.Sort(byRoute(routes))
+	return routes
+}
+
+// byRoute sorts routes by domain, pattern and method
+type byRoute []backupRoute
+
+func (r byRoute) Len() int      { return len(r) }
+func (r byRoute) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r byRoute) Less(i, j int) bool {
+	return r[i].Domain+r[i].Pattern+r[i].Method < r[j].Domain+r[j].Pattern+r[j].Method
+}
+
+// fetchWebhooks returns the webhook URLs of an application
+func fetchWebhooks(app string) []string {
+	var hooks []webhookStruct
+	var urls []string
+
+	b := internal.GetWantJSON(fmt.Sprintf("/applications/%s/hook", app))
+	internal.Check(json.Unmarshal(b, &hooks))
+
+	for _, hook := range hooks {
+		urls = append(urls, hook.URL)
+	}
+	return urls
+}
+
+// fetchRepositories returns the repositories of an application
+func fetchRepositories(app string) []backupRepository {
+	var names []string
+	var repositories []backupRepository
+
+	b := internal.ReqWant("GET", http.StatusOK, fmt.Sprintf("/repositories/%s", app), nil)
+	internal.Check(json.Unmarshal(b, &names))
+
+	for _, name := range names {
+		var repository backupRepository
+		b := internal.ReqWant("GET", http.StatusOK, fmt.Sprintf("/repositories/%s/%s", app, name), nil)
+		internal.Check(json.Unmarshal(b, &repository))
+		repository.Name = name
+		repositories = append(repositories, repository)
+	}
+	return repositories
+}
+
+// writeBackup writes an archive. It holds secrets, such as environment
+// variables, so it is only readable by its owner.
+func writeBackup(file string, backup appBackup) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	documents := []struct {
+		name string
+		doc  interface{}
+	}{
+		{"manifest.json", backup.Manifest},
+		{"env.json", backup.Env},
+		{"networks.json", backup.Networks},
+		{"routes.json", backup.Routes},
+		{"webhooks.json", backup.Webhooks},
+		{"repositories.json", backup.Repositories},
+	}
+	for _, spec := range backup.Services {
+		documents = append(documents, struct {
+			name string
+			doc  interface{}
+		}{"services/" + spec.Service + ".json", spec})
+	}
+
+	for _, d := range documents {
+		data, err := json.MarshalIndent(d.doc, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		hdr := &tar.Header{Name: d.name, Mode: 0600, Size: int64(len(data)), ModTime: time.Now()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readBackup reads an archive written by writeBackup
+func readBackup(file string) (appBackup, error) {
+	var backup appBackup
+
+	f, err := os.Open(file)
+	if err != nil {
+		return backup, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return backup, fmt.Errorf("%s: %s", file, err)
+	}
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return backup, fmt.Errorf("%s: %s", file, err)
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return backup, err
+		}
+
+		var target interface{}
+		switch {
+		case hdr.Name == "manifest.json":
+			target = &backup.Manifest
+		case hdr.Name == "env.json":
+			target = &backup.Env
+		case hdr.Name == "networks.json":
+			target = &backup.Networks
+		case hdr.Name == "routes.json":
+			target = &backup.Routes
+		case hdr.Name == "webhooks.json":
+			target = &backup.Webhooks
+		case hdr.Name == "repositories.json":
+			target = &backup.Repositories
+		case path.Dir(hdr.Name) == "services" && strings.HasSuffix(hdr.Name, ".json"):
+			backup.Services = append(backup.Services, service.ServiceSpec{})
+			target = &backup.Services[len(backup.Services)-1]
+		default:
+			fmt.Fprintf(os.Stderr, "Warning: ignoring unknown file %s in %s\n", hdr.Name, file)
+			continue
+		}
+
+		if err := json.Unmarshal(data, target); err != nil {
+			return backup, fmt.Errorf("%s: %s: %s", file, hdr.Name, err)
+		}
+	}
+
+	if backup.Manifest.Version == 0 {
+		return backup, fmt.Errorf("%s: not an application backup, manifest.json is missing", file)
+	} else if backup.Manifest.Version > backupVersion {
+		return backup, fmt.Errorf("%s: archive version %d is not supported by this version of sail (max %d)", file, backup.Manifest.Version, backupVersion)
+	}
+
+	return backup, nil
+}
@@ -0,0 +1,95 @@
+package application
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/network"
+	"github.com/runabove/sail/service"
+	"github.com/spf13/cobra"
+)
+
+var cloneWithDomains bool
+
+func cloneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clone",
+		Short: "Copy all services of an application into another one: sail application clone <fromApplication> <toApplication> [--with-domains]",
+		Long: `Copy all services of an application into another one: sail application clone <fromApplication> <toApplication> [--with-domains]
+
+Environment variables and private networks (with their ranges) are copied
+first, then services are created in link order, so that each service is
+created after the services it links to. References to <fromApplication> are
+rewritten to <toApplication>. Attached domains are only copied with --with-domains.
+	"example: sail application clone myapp myapp-staging"
+`,
+		Run: cmdClone,
+	}
+
+	cmd.Flags().BoolVar(&cloneWithDomains, "with-domains", false, "also attach the domains of the source services")
+
+	return cmd
+}
+
+func cmdClone(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail application clone <fromApplication> <toApplication>. Please see sail application clone --help")
+		os.Exit(1)
+	}
+	from, to := args[0], args[1]
+
+	for _, app := range args {
+		internal.Check(internal.CheckName(app))
+	}
+
+	if from == to {
+		fmt.Fprintln(os.Stderr, "Error: source and target applications must differ")
+		os.Exit(1)
+	}
+
+	// Fetch and order services before creating anything
+	specs := make(map[string]service.ServiceSpec)
+	for _, name := range service.ListServices(from) {
+		specs[name] = service.FetchServiceSpec(from, name)
+	}
+
+	order, err := service.LinkOrder(specs)
+	internal.Check(err)
+
+	// Environment
+	current := envList(to)
+	for _, change := range envDiff(current, envList(from)) {
+		if change.op == "+" {
+			envSet(to, change.key, change.value)
+			fmt.Fprintf(os.Stderr, "Set environment variable %s on %s\n", change.key, to)
+		} else if change.op == "~" {
+			fmt.Fprintf(os.Stderr, "Keeping environment variable %s of %s\n", change.key, to)
+		}
+	}
+
+	// Networks
+	existing := make(map[string]bool)
+	for _, name := range network.List(to) {
+		existing[name] = true
+	}
+	for _, name := range network.List(from) {
+		target := name
+		if name == from {
+			target = to
+		}
+		if !existing[target] {
+			network.Copy(from, name, to, target)
+		}
+	}
+
+	// Services
+	for _, name := range order {
+		service.CreateService(service.RewriteSpec(specs[name], to, name))
+		if cloneWithDomains {
+			service.CopyRoutes(from, name, to, name)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Cloned %d service(s) from %s to %s\n", len(order), from, to)
+}
@@ -0,0 +1,188 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
+	"github.com/spf13/cobra"
+)
+
+var cmdApplicationDiff = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare the configuration of two applications: sail application diff <applicationName> <otherApplicationName>",
+	Long: `Compare the configuration of two applications: sail application diff <applicationName> <otherApplicationName>
+
+Compares services (repository, tag, model, number of containers and environment),
+application environment variables, private networks and attached domains.
+	"example: sail application diff myapp-staging myapp-prod"
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "Invalid usage. sail application diff <applicationName> <otherApplicationName>. Please see sail application diff --help")
+			os.Exit(1)
+		}
+
+		for _, app := range args {
+			internal.Check(internal.CheckName(app))
+		}
+
+		diff := appDiff{From: args[0], To: args[1], Changes: applicationDiff(args[0], args[1])}
+		data, err := json.Marshal(diff)
+		internal.Check(err)
+
+		internal.FormatOutput(data, appDiffFormatter)
+	},
+}
+
+// appDiff holds all differences between 2 applications
+type appDiff struct {
+	From    string      `json:"from"`
+	To      string      `json:"to"`
+	Changes []diffEntry `json:"changes"`
+}
+
+// diffEntry is a single difference. An empty value means missing.
+type diffEntry struct {
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// applicationDiff compares services, env, networks and domains of 2 applications
+func applicationDiff(from, to string) []diffEntry {
+	var changes []diffEntry
+
+	changes = append(changes, diffMaps("service", servicesSummary(from), servicesSummary(to))...)
+	changes = append(changes, diffMaps("env", envList(from), envList(to))...)
+	changes = append(changes, diffMaps("network", networksSummary(from), networksSummary(to))...)
+	changes = append(changes, diffMaps("domain", domainsSummary(from), domainsSummary(to))...)
+
+	return changes
+}
+
+// diffMaps compares 2 maps, sorted by key
+func diffMaps(section string, from, to map[string]string) []diffEntry {
+	var changes []diffEntry
+
+	keys := make(map[string]bool)
+	for key := range from {
+		keys[key] = true
+	}
+	for key := range to {
+		keys[key] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		f, inFrom := from[key]
+		t, inTo := to[key]
+		if inFrom != inTo || f != t {
+			changes = append(changes, diffEntry{Section: section, Name: key, From: f, To: t})
+		}
+	}
+
+	return changes
+}
+
+// servicesSummary flattens the comparable fields of every service of an application.
+// Keys are of the form service[.field]
+func servicesSummary(app string) map[string]string {
+	summary := make(map[string]string)
+
+	for _, name := range service.ListServices(app) {
+		spec := service.FetchServiceSpec(app, name)
+
+		summary[name] = spec.Repository + ":" + spec.RepositoryTag
+		summary[name+".model"] = spec.ContainerModel
+		summary[name+".number"] = fmt.Sprint(spec.ContainerNumber)
+		for _, kv := range spec.ContainerEnvironment {
+			parsed := strings.SplitN(kv, "=", 2)
+			if len(parsed) == 2 {
+				summary[name+".env."+parsed[0]] = parsed[1]
+			}
+		}
+	}
+
+	return summary
+}
+
+// networksSummary returns subnet and ranges of every private network of an application
+func networksSummary(app string) map[string]string {
+	var networks []string
+	summary := make(map[string]string)
+
+	b := internal.GetWantJSON(fmt.Sprintf("/applications/%s/networks", app))
+	internal.Check(json.Unmarshal(b, &networks))
+
+	for _, name := range networks {
+		var network map[string]interface{}
+		var ranges []string
+
+		b := internal.GetWantJSON(fmt.Sprintf("/applications/%s/networks/%s", app, name))
+		internal.Check(json.Unmarshal(b, &network))
+		b = internal.GetWantJSON(fmt.Sprintf("/applications/%s/networks/%s/ranges", app, name))
+		internal.Check(json.Unmarshal(b, &ranges))
+
+		sort.Strings(ranges)
+		summary[name] = strings.TrimSpace(fmt.Sprintf("%v %s", network["subnet"], strings.Join(ranges, ",")))
+	}
+
+	return summary
+}
+
+// domainsSummary returns the service of every route attached to an application.
+// Keys are of the form 'method domain/pattern'
+func domainsSummary(app string) map[string]string {
+	var domains map[string][]map[string]interface{}
+	summary := make(map[string]string)
+
+	b := internal.GetWantJSON(fmt.Sprintf("/applications/%s/attached-domains", app))
+	internal.Check(json.Unmarshal(b, &domains))
+
+	for domain, routes := range domains {
+		for _, route := range routes {
+			key := fmt.Sprintf("%v %s%v", route["method"], domain, route["pattern"])
+			summary[key] = fmt.Sprint(route["service"])
+		}
+	}
+
+	return summary
+}
+
+func appDiffFormatter(data []byte) {
+	var diff appDiff
+	internal.Check(json.Unmarshal(data, &diff))
+
+	if len(diff.Changes) == 0 {
+		fmt.Fprintf(os.Stderr, "No difference between %s and %s\n", diff.From, diff.To)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	titles := []string{"SECTION", "NAME", strings.ToUpper(diff.From), strings.ToUpper(diff.To)}
+	fmt.Fprintln(w, strings.Join(titles, "\t"))
+
+	for _, change := range diff.Changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.Section, change.Name, orDash(change.From), orDash(change.To))
+	}
+	w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.Replace(s, "\n", "\\n", -1)
+}
@@ -13,6 +13,7 @@ import (
 )
 
 var domainHeadersDone = false
+var domainDetachYes bool
 
 var cmdApplicationDomain = &cobra.Command{
 	Use:     "domain",
@@ -45,8 +46,12 @@ var cmdApplicationDomainList = &cobra.Command{
 
 var cmdApplicationDomainDetach = &cobra.Command{
 	Use:     "detach",
-	Short:   "Detach a domain from the HTTP load balancer: sail application domain detach <applicationName> <domainName>",
 	Aliases: []string{"add"},
+	Short:   "Detach a domain from the HTTP load balancer: sail application domain detach <applicationName> <domainName>",
+	Long: `Detach a domain from the HTTP load balancer: sail application domain detach <applicationName> <domainName>
+
+When stdin is a terminal, the routes of the domain are listed and confirmation
+is asked, unless --yes is set.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 2 {
 			fmt.Fprintln(os.Stderr, "Invalid usage. Please see sail application domain attach --help")
@@ -57,6 +62,12 @@ var cmdApplicationDomainDetach = &cobra.Command{
 			err = internal.CheckName(args[1])
 			internal.Check(err)
 
+			question := fmt.Sprintf("Detach domain %s from application %s?", args[1], args[0])
+			if !internal.ConfirmImpact(domainDetachYes, question, func() []string { return domainImpact(args[0], args[1]) }) {
+				fmt.Fprintln(os.Stderr, "Aborted")
+				os.Exit(1)
+			}
+
 			path := fmt.Sprintf("/applications/%s/attached-domains/%s", args[0], args[1])
 			data := internal.DeleteWantJSON(path)
 
@@ -117,3 +128,17 @@ func domainListFormatter(data []byte) {
 		}
 	}
 }
+
+// domainImpact lists the routes of a domain, which will not be served anymore
+func domainImpact(app, domain string) []string {
+	var domains map[string][]map[string]interface{}
+	if err := internal.GetJSON(fmt.Sprintf("/applications/%s/attached-domains", app), &domains); err != nil {
+		return internal.ImpactUnknown(err)
+	}
+
+	var impact []string
+	for _, route := range domains[domain] {
+		impact = append(impact, fmt.Sprintf("route %s %s%s to service %s/%s will lose routing", route["method"], domain, route["pattern"], app, route["service"]))
+	}
+	return impact
+}
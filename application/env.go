@@ -3,10 +3,17 @@ package application
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/runabove/sail/internal"
-	"github.com/spf13/cobra"
 	"os"
+	"sort"
 	"strings"
+
+	"github.com/runabove/sail/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	envSyncDryRun bool
+	envSyncYes    bool
 )
 
 type env struct {
@@ -33,6 +40,44 @@ var cmdApplicationSetEnv = &cobra.Command{
 	Run:     cmdSetEnv,
 }
 
+var cmdApplicationImportEnv = &cobra.Command{
+	Use:   "import",
+	Short: "Import environment variables from a .env file: sail application env import [<applicationName>] <file>",
+	Long: `Import environment variables from a .env file: sail application env import [<applicationName>] <file>
+
+The file uses Docker .env syntax: one KEY=VALUE per line, '#' comments, single
+or double quoted values which may span multiple lines. Existing variables with
+the same name are overwritten, other variables are left untouched.
+`,
+	Run: cmdImportEnv,
+}
+
+var cmdApplicationExportEnv = &cobra.Command{
+	Use:   "export",
+	Short: "Export environment variables as a .env file: sail application env export [<applicationName>] > .env",
+	Run:   cmdExportEnv,
+}
+
+func envSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Make environment variables match a .env file: sail application env sync [<applicationName>] <file> [--dry-run]",
+		Long: `Make environment variables match a .env file: sail application env sync [<applicationName>] <file> [--dry-run]
+
+Variables missing from the application are added, variables with a different
+value are updated and variables which are not in the file are deleted. The
+list of changes is displayed before being applied. When stdin is a terminal,
+confirmation is asked, unless --yes is set.
+`,
+		Run: cmdSyncEnv,
+	}
+
+	cmd.Flags().BoolVar(&envSyncDryRun, "dry-run", false, "only display changes, do not apply them")
+	cmd.Flags().BoolVar(&envSyncYes, "yes", false, "do not ask for confirmation")
+
+	return cmd
+}
+
 var cmdApplicationDelEnv = &cobra.Command{
 	Use:     "delete",
 	Short:   "Delete an environment variable for given application: sail application env delete [<applicationName>] <KEY>",
@@ -57,7 +102,6 @@ func cmdListEnv(cmd *cobra.Command, args []string) {
 }
 
 func cmdSetEnv(cmd *cobra.Command, args []string) {
-	var cmdEnvBody env
 	var applicationName string
 	var parsedData []string
 
@@ -73,10 +117,175 @@ func cmdSetEnv(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	cmdEnvBody.Data = parsedData[1]
-	jsonStr, err := json.Marshal(cmdEnvBody)
+	if len(parsedData) != 2 || parsedData[0] == "" {
+		fmt.Fprintln(os.Stderr, "Invalid usage. Variable should be of form KEY=VALUE. Please see sail application env set --help")
+		os.Exit(1)
+	}
+
+	internal.FormatOutputDef(envSet(applicationName, parsedData[0], parsedData[1]))
+}
+
+func cmdImportEnv(cmd *cobra.Command, args []string) {
+	var applicationName string
+	var file string
+
+	switch len(args) {
+	case 1:
+		applicationName = internal.GetUserName()
+		file = args[0]
+	case 2:
+		applicationName = args[0]
+		file = args[1]
+	default:
+		fmt.Fprintln(os.Stderr, "Invalid usage. Please see sail application env import --help")
+		return
+	}
+
+	env, err := internal.ReadEnvFile(file)
+	internal.Check(err)
+
+	for _, kv := range env {
+		parsedData := strings.SplitN(kv, "=", 2)
+		envSet(applicationName, parsedData[0], parsedData[1])
+		if internal.Format == "pretty" {
+			fmt.Fprintf(os.Stderr, "Set %s\n", parsedData[0])
+		}
+	}
+
+	internal.FormatOutputDef(internal.GetWantJSON(fmt.Sprintf("/applications/%s/env", applicationName)))
+}
+
+func cmdExportEnv(cmd *cobra.Command, args []string) {
+	var applicationName string
+
+	switch len(args) {
+	case 0:
+		applicationName = internal.GetUserName()
+	case 1:
+		applicationName = args[0]
+	default:
+		fmt.Fprintln(os.Stderr, "Invalid usage. Please see sail application env export --help")
+		return
+	}
+
+	vars := envList(applicationName)
+	for _, key := range sortedEnvKeys(vars) {
+		fmt.Println(internal.FormatEnvLine(key, vars[key]))
+	}
+}
+
+func cmdSyncEnv(cmd *cobra.Command, args []string) {
+	var applicationName string
+	var file string
+
+	switch len(args) {
+	case 1:
+		applicationName = internal.GetUserName()
+		file = args[0]
+	case 2:
+		applicationName = args[0]
+		file = args[1]
+	default:
+		fmt.Fprintln(os.Stderr, "Invalid usage. Please see sail application env sync --help")
+		return
+	}
+
+	env, err := internal.ReadEnvFile(file)
+	internal.Check(err)
+
+	wanted := make(map[string]string)
+	for _, kv := range env {
+		parsedData := strings.SplitN(kv, "=", 2)
+		wanted[parsedData[0]] = parsedData[1]
+	}
+
+	diff := envDiff(envList(applicationName), wanted)
+	if len(diff) == 0 {
+		fmt.Fprintf(os.Stderr, "Environment of %s is up to date\n", applicationName)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Changes to environment of %s:\n", applicationName)
+	for _, change := range diff {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", change.op, change.key)
+	}
+
+	if envSyncDryRun {
+		return
+	}
+
+	// The changes were just listed, they are the impact
+	question := fmt.Sprintf("Apply %d changes to environment of %s?", len(diff), applicationName)
+	if !internal.ConfirmImpact(envSyncYes, question, func() []string { return nil }) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(1)
+	}
+
+	envApply(applicationName, diff)
+	fmt.Fprintf(os.Stderr, "Applied %d changes\n", len(diff))
+}
+
+// envChange is a single change to apply on an application environment
+type envChange struct {
+	op    string // one of '+' (add), '~' (update), '-' (delete)
+	key   string
+	value string
+}
+
+// envDiff computes changes to turn current into wanted, sorted by key
+func envDiff(current, wanted map[string]string) []envChange {
+	var diff []envChange
+
+	for _, key := range sortedEnvKeys(wanted) {
+		val, ok := current[key]
+		if !ok {
+			diff = append(diff, envChange{op: "+", key: key, value: wanted[key]})
+		} else if val != wanted[key] {
+			diff = append(diff, envChange{op: "~", key: key, value: wanted[key]})
+		}
+	}
+	for _, key := range sortedEnvKeys(current) {
+		if _, ok := wanted[key]; !ok {
+			diff = append(diff, envChange{op: "-", key: key})
+		}
+	}
+
+	return diff
+}
+
+// envApply applies a list of changes on an application environment
+func envApply(applicationName string, diff []envChange) {
+	for _, change := range diff {
+		if change.op == "-" {
+			internal.DeleteWantJSON(fmt.Sprintf("/applications/%s/env/%s", applicationName, change.key))
+		} else {
+			envSet(applicationName, change.key, change.value)
+		}
+	}
+}
+
+// envList returns environment variables of an application
+func envList(applicationName string) map[string]string {
+	vars := make(map[string]string)
+	b := internal.GetWantJSON(fmt.Sprintf("/applications/%s/env", applicationName))
+	internal.Check(json.Unmarshal(b, &vars))
+	return vars
+}
+
+func sortedEnvKeys(vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// envSet sets a single environment variable of an application
+func envSet(applicationName, key, value string) []byte {
+	jsonStr, err := json.Marshal(env{Data: value})
 	internal.Check(err)
-	internal.FormatOutputDef(internal.PostBodyWantJSON(fmt.Sprintf("/applications/%s/env/%s", applicationName, parsedData[0]), jsonStr))
+	return internal.PostBodyWantJSON(fmt.Sprintf("/applications/%s/env/%s", applicationName, key), jsonStr)
 }
 
 func cmdDelEnv(cmd *cobra.Command, args []string) {
@@ -0,0 +1,190 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
+)
+
+var (
+	eventsTypes    []string
+	eventsStates   []string
+	eventsSince    time.Duration
+	eventsInterval time.Duration
+)
+
+func eventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Stream the events of all services of an application: sail application events <applicationName>",
+		Long: `Stream the events of all services of an application: sail application events <applicationName>
+
+The event streams of the services are followed at the same time and merged, each
+event prefixed with its service. Services added to the application are followed
+as soon as they are found, every --interval. Streams are reopened when they close
+or fail.
+
+Events may be filtered by --type, such as 'state', and by --state, such as
+'stopped'. With --since, the events of the last period are replayed first, where
+the API supports it. With --format json, events are printed as one JSON object
+per line.
+	"example: sail application events my-app --state stopped --since 1h"
+`,
+		Run: cmdEvents,
+	}
+
+	cmd.Flags().StringSliceVar(&eventsTypes, "type", nil, "only print events of these types")
+	cmd.Flags().StringSliceVar(&eventsStates, "state", nil, "only print events to these states")
+	cmd.Flags().DurationVar(&eventsSince, "since", 0, "replay the events of this last period first")
+	cmd.Flags().DurationVar(&eventsInterval, "interval", 10*time.Second, "time between two checks for new services")
+
+	return cmd
+}
+
+func cmdEvents(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail application events <applicationName>. Please see sail application events --help")
+		os.Exit(1)
+	}
+	app := args[0]
+	internal.Check(internal.CheckName(app))
+
+	if eventsInterval < time.Second {
+		internal.Exit("Error: --interval must be at least 1s\n")
+	}
+
+	// Filters are case insensitive
+	eventsTypes = internal.ToLower(eventsTypes)
+	eventsStates = internal.ToLower(eventsStates)
+
+	var since float64
+	if eventsSince > 0 {
+		since = float64(time.Now().Add(-eventsSince).UnixNano()) / 1e9
+	}
+
+	m := &eventMux{
+		app:        app,
+		since:      since,
+		events:     make(chan *internal.Event),
+		subscribed: make(map[string]chan struct{}),
+	}
+	m.run()
+}
+
+// eventMux merges the event streams of the services of an application
+type eventMux struct {
+	app        string
+	since      float64
+	events     chan *internal.Event
+	subscribed map[string]chan struct{}
+	width      int // of the longest service name, to align events
+}
+
+func (m *eventMux) run() {
+	ticker := time.NewTicker(eventsInterval)
+	defer ticker.Stop()
+
+	// --since only applies to the services found at start
+	m.rescan(false)
+	m.since = 0
+
+	for {
+		select {
+		case ev := <-m.events:
+			if matchEvent(ev) {
+				m.print(ev)
+			}
+		case <-ticker.C:
+			m.rescan(true)
+		}
+	}
+}
+
+// rescan subscribes to the services added to the application and unsubscribes
+// from the deleted ones
+func (m *eventMux) rescan(announce bool) {
+	var names []string
+	b, code, err := internal.Request("GET", fmt.Sprintf("/applications/%s/services", m.app), nil)
+	if err == nil && code != http.StatusOK {
+		err = fmt.Errorf("unexpected status code %d", code)
+		if e := internal.DecodeError(b); e != nil {
+			err = e
+		}
+	}
+	if err == nil {
+		err = json.Unmarshal(b, &names)
+	}
+	if err != nil {
+		if !announce {
+			internal.Check(err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: could not list the services of %s: %s\n", m.app, err)
+		return
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := m.subscribed[name]; ok {
+			continue
+		}
+		if announce {
+			fmt.Fprintf(os.Stderr, "Following new service %s/%s\n", m.app, name)
+		}
+		if len(name) > m.width {
+			m.width = len(name)
+		}
+
+		done := make(chan struct{})
+		m.subscribed[name] = done
+		go service.SubscribeEvents(m.app, name, m.since, m.events, done, func(message string) {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", message)
+		})
+	}
+
+	for name, done := range m.subscribed {
+		if !internal.StringIn(name, names) {
+			fmt.Fprintf(os.Stderr, "Service %s/%s was deleted\n", m.app, name)
+			close(done)
+			delete(m.subscribed, name)
+		}
+	}
+}
+
+// matchEvent tells whether an event passes the --type and --state filters
+func matchEvent(ev *internal.Event) bool {
+	if len(eventsTypes) > 0 && !internal.StringIn(strings.ToLower(ev.Event), eventsTypes) {
+		return false
+	}
+	if len(eventsStates) > 0 && !internal.StringIn(strings.ToLower(ev.State), eventsStates) {
+		return false
+	}
+	return true
+}
+
+func (m *eventMux) print(ev *internal.Event) {
+	data, err := json.Marshal(ev)
+	internal.Check(err)
+
+	// One event per line, not indented
+	if internal.Format == "json" {
+		fmt.Println(string(data))
+		return
+	}
+
+	internal.FormatOutput(data, func(data []byte) {
+		when := time.Now()
+		if ev.Timestamp > 0 {
+			when = time.Unix(0, int64(ev.Timestamp*1e9))
+		}
+		fmt.Printf("%s %-*s %s\n", when.Format("2006-01-02 15:04:05"), m.width, ev.Service, service.DescribeEvent(ev))
+	})
+}
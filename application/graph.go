@@ -0,0 +1,256 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
+)
+
+var graphType string
+
+func graphCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Export the service graph of an application: sail application graph <applicationName> [--type dot|mermaid]",
+		Long: `Export the service graph of an application: sail application graph <applicationName> [--type dot|mermaid]
+
+The graph holds the links between services, the networks they are connected to
+and the domains routed to them. Links to services which do not exist anymore are
+flagged as dangling. It is printed as Graphviz DOT or Mermaid, or as JSON with
+--format json.
+	"example: sail application graph my-app | dot -Tsvg > my-app.svg"
+`,
+		Run: cmdGraph,
+	}
+
+	cmd.Flags().StringVar(&graphType, "type", "dot", "graph language, 'dot' or 'mermaid'")
+
+	return cmd
+}
+
+// appGraph is the service graph of an application
+type appGraph struct {
+	Application string         `json:"application"`
+	Services    []graphService `json:"services"`
+	Networks    []string       `json:"networks"`
+	Domains     []string       `json:"domains"`
+	Dangling    []string       `json:"dangling"`
+}
+
+type graphService struct {
+	Name       string      `json:"name"`
+	Repository string      `json:"repository"`
+	Tag        string      `json:"repository_tag"`
+	Links      []graphLink `json:"links"`
+	Networks   []string    `json:"networks"`
+	Domains    []string    `json:"domains"`
+}
+
+type graphLink struct {
+	Target   string `json:"target"`
+	Alias    string `json:"alias"`
+	Dangling bool   `json:"dangling,omitempty"`
+}
+
+func cmdGraph(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail application graph <applicationName>. Please see sail application graph --help")
+		os.Exit(1)
+	}
+	app := args[0]
+	internal.Check(internal.CheckName(app))
+
+	var printGraph func(appGraph)
+	switch graphType {
+	case "dot":
+		printGraph = printDot
+	case "mermaid":
+		printGraph = printMermaid
+	default:
+		internal.Exit("Error: Invalid graph type '%s'. Must be one of 'dot' and 'mermaid'\n", graphType)
+	}
+
+	specs, err := service.Snapshot(app)
+	internal.Check(err)
+
+	g := buildGraph(app, specs)
+	for _, name := range g.Dangling {
+		fmt.Fprintf(os.Stderr, "Warning: dangling link to %s: no such service in %s\n", name, app)
+	}
+
+	data, err := json.Marshal(g)
+	internal.Check(err)
+
+	internal.FormatOutput(data, func(data []byte) {
+		printGraph(g)
+	})
+}
+
+func buildGraph(app string, specs map[string]service.ServiceSpec) appGraph {
+	g := appGraph{Application: app}
+	networks := make(map[string]bool)
+	domains := make(map[string]bool)
+	dangling := make(map[string]bool)
+
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		spec := specs[name]
+		s := graphService{
+			Name:       name,
+			Repository: spec.Repository,
+			Tag:        spec.RepositoryTag,
+			Links:      []graphLink{},
+			Networks:   []string{},
+			Domains:    []string{},
+		}
+
+		for _, target := range sortedKeys(spec.Links) {
+			_, exists := specs[target]
+			s.Links = append(s.Links, graphLink{Target: target, Alias: spec.Links[target], Dangling: !exists})
+			if !exists {
+				dangling[target] = true
+			}
+		}
+
+		for network := range spec.ContainerNetwork {
+			s.Networks = append(s.Networks, network)
+			networks[network] = true
+		}
+		sort.Strings(s.Networks)
+
+		for _, route := range service.ListRoutes(app, name) {
+			if route.Domain == "" || internal.StringIn(route.Domain, s.Domains) {
+				continue
+			}
+			s.Domains = append(s.Domains, route.Domain)
+			domains[route.Domain] = true
+		}
+		sort.Strings(s.Domains)
+
+		g.Services = append(g.Services, s)
+	}
+
+	g.Networks = sortedSet(networks)
+	g.Domains = sortedSet(domains)
+	g.Dangling = sortedSet(dangling)
+	return g
+}
+
+func printDot(g appGraph) {
+	fmt.Printf("digraph %q {\n", g.Application)
+	fmt.Println("  rankdir=LR;")
+	fmt.Println("  node [shape=box];")
+
+	for _, s := range g.Services {
+		fmt.Printf("  %q [label=%q];\n", "service:"+s.Name, fmt.Sprintf("%s\n%s:%s", s.Name, s.Repository, s.Tag))
+	}
+	for _, name := range g.Dangling {
+		fmt.Printf("  %q [label=%q, style=dashed, color=red];\n", "service:"+name, name+"\n(missing)")
+	}
+	for _, network := range g.Networks {
+		fmt.Printf("  %q [label=%q, shape=ellipse];\n", "network:"+network, network)
+	}
+	for _, domain := range g.Domains {
+		fmt.Printf("  %q [label=%q, shape=note];\n", "domain:"+domain, domain)
+	}
+
+	for _, s := range g.Services {
+		for _, link := range s.Links {
+			attrs := fmt.Sprintf("label=%q", link.Alias)
+			if link.Dangling {
+				attrs += ", style=dashed, color=red"
+			}
+			fmt.Printf("  %q -> %q [%s];\n", "service:"+s.Name, "service:"+link.Target, attrs)
+		}
+		for _, network := range s.Networks {
+			fmt.Printf("  %q -> %q [arrowhead=none, style=dotted];\n", "service:"+s.Name, "network:"+network)
+		}
+		for _, domain := range s.Domains {
+			fmt.Printf("  %q -> %q;\n", "domain:"+domain, "service:"+s.Name)
+		}
+	}
+	fmt.Println("}")
+}
+
+// mermaidID returns a node identifier only made of characters mermaid accepts.
+// Other bytes of name are escaped as _<hex> and '_' as __, so that distinct
+// names never share an identifier.
+func mermaidID(kind, name string) string {
+	id := []byte(kind + "_")
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			id = append(id, c)
+		case c == '_':
+			id = append(id, "__"...)
+		default:
+			id = append(id, fmt.Sprintf("_%02x", c)...)
+		}
+	}
+	return string(id)
+}
+
+func printMermaid(g appGraph) {
+	fmt.Println("graph LR")
+
+	for _, s := range g.Services {
+		fmt.Printf("  %s[\"%s<br/>%s:%s\"]\n", mermaidID("service", s.Name), s.Name, s.Repository, s.Tag)
+	}
+	for _, name := range g.Dangling {
+		fmt.Printf("  %s[\"%s<br/>(missing)\"]:::dangling\n", mermaidID("service", name), name)
+	}
+	for _, network := range g.Networks {
+		fmt.Printf("  %s((\"%s\"))\n", mermaidID("network", network), network)
+	}
+	for _, domain := range g.Domains {
+		fmt.Printf("  %s>\"%s\"]\n", mermaidID("domain", domain), domain)
+	}
+
+	for _, s := range g.Services {
+		for _, link := range s.Links {
+			arrow := "-->"
+			if link.Dangling {
+				arrow = "-.->"
+			}
+			fmt.Printf("  %s %s|%s| %s\n", mermaidID("service", s.Name), arrow, link.Alias, mermaidID("service", link.Target))
+		}
+		for _, network := range s.Networks {
+			fmt.Printf("  %s --- %s\n", mermaidID("service", s.Name), mermaidID("network", network))
+		}
+		for _, domain := range s.Domains {
+			fmt.Printf("  %s --> %s\n", mermaidID("domain", domain), mermaidID("service", s.Name))
+		}
+	}
+	if len(g.Dangling) > 0 {
+		fmt.Println("  classDef dangling stroke:#f00,stroke-dasharray:5 5")
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedSet(set map[string]bool) []string {
+	values := make([]string, 0, len(set))
+	for value := range set {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
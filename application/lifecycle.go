@@ -0,0 +1,124 @@
+package application
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
+)
+
+var (
+	lifecycleExclude []string
+	lifecycleTimeout time.Duration
+)
+
+func startCmd() *cobra.Command {
+	return lifecycleCmd("start", "Start all services of an application", `Services are started in link order: a service is started once the services it
+links to are running.`, startServices)
+}
+
+func stopCmd() *cobra.Command {
+	return lifecycleCmd("stop", "Stop all services of an application", `Services are stopped in reverse link order: a service is stopped once the
+services linking to it are stopped.`, stopServices)
+}
+
+func restartCmd() *cobra.Command {
+	return lifecycleCmd("restart", "Restart all services of an application", `Services are stopped in reverse link order, then started in link order.`, func(app string, order []string) {
+		stopServices(app, order)
+		startServices(app, order)
+	})
+}
+
+// lifecycleCmd builds an application command running fn on the services of an
+// application sorted in link order
+func lifecycleCmd(use, short, long string, fn func(app string, order []string)) *cobra.Command {
+	usage := fmt.Sprintf("sail application %s <applicationName> [--exclude <serviceId>,...]", use)
+
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: fmt.Sprintf("%s: %s", short, usage),
+		Long: fmt.Sprintf(`%s: %s
+
+%s
+The command fails if links form a cycle, and stops at the first service not
+reaching the wanted state within --timeout.
+	"example: sail application %s my-app --exclude cron"
+`, short, usage, long, use),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				fmt.Fprintf(os.Stderr, "Invalid usage. %s. Please see sail application %s --help\n", usage, use)
+				os.Exit(1)
+			}
+			app := args[0]
+			internal.Check(internal.CheckName(app))
+
+			fn(app, linkOrder(app, lifecycleExclude))
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&lifecycleExclude, "exclude", nil, "services to leave untouched")
+	cmd.Flags().DurationVar(&lifecycleTimeout, "timeout", 5*time.Minute, "maximum time to wait for each service")
+
+	return cmd
+}
+
+// linkOrder returns the services of an application in link order, but the
+// excluded ones
+func linkOrder(app string, exclude []string) []string {
+	specs, err := service.Snapshot(app)
+	internal.Check(err)
+
+	for _, name := range exclude {
+		if _, ok := specs[name]; !ok {
+			internal.Exit("Error: can not exclude %s: no such service in %s\n", name, app)
+		}
+		delete(specs, name)
+	}
+
+	order, err := service.LinkOrder(specs)
+	internal.Check(err)
+
+	return order
+}
+
+func startServices(app string, order []string) {
+	for i, name := range order {
+		if service.State(app, name) == "running" {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s/%s is already running\n", i+1, len(order), app, name)
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "[%d/%d] Starting %s/%s...\n", i+1, len(order), app, name)
+		service.StartService(app, name)
+		waitServiceState(app, name, "running")
+	}
+}
+
+func stopServices(app string, order []string) {
+	for i := range order {
+		name := order[len(order)-1-i]
+		if service.State(app, name) == "stopped" {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s/%s is already stopped\n", i+1, len(order), app, name)
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "[%d/%d] Stopping %s/%s...\n", i+1, len(order), app, name)
+		service.StopService(app, name)
+		waitServiceState(app, name, "stopped")
+	}
+}
+
+// waitServiceState waits for a service to reach state and exits on failure
+func waitServiceState(app, name, state string) {
+	err := service.WaitState(app, name, state, lifecycleTimeout, func(ev *internal.Event) error {
+		service.PrintTransition(ev)
+		return service.UnexpectedTransition(ev, state)
+	})
+	if err != nil {
+		service.ExitWait(fmt.Sprintf("%s/%s", app, name), state, lifecycleTimeout, err)
+	}
+}
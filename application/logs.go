@@ -0,0 +1,236 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
+)
+
+var (
+	logsServices []string
+	logsTail     int
+	logsSearch   string
+	logsPeriod   string
+	logsFollow   bool
+	logsSince    time.Duration
+	logsInterval time.Duration
+	logsNoColor  bool
+)
+
+// maximum number of services whose logs are fetched at the same time
+const logsConcurrency = 8
+
+// colors of the service prefixes, in order of the services
+var logsColors = []string{"36", "33", "32", "35", "34", "31", "1;36", "1;33", "1;32", "1;35", "1;34", "1;31"}
+
+func logsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Logs of all services of an application: sail application logs <applicationName> [--services <serviceId>,...] [--follow]",
+		Long: `Logs of all services of an application: sail application logs <applicationName> [--services <serviceId>,...] [--follow]
+
+The logs of the services are fetched at the same time and merged by timestamp.
+Each line is prefixed with its service and container, colored by service when
+stdout is a terminal, unless --no-color is set. --tail, --search and --period
+apply to each service. With --format json, lines are printed as one JSON object
+per line.
+
+With --follow, the logs are polled every --interval and new lines are printed
+until interrupted. --since prints the lines of the last period first, instead
+of the --tail last ones.
+	"example: sail application logs my-app --services web,worker --tail 200 --follow"
+`,
+		Run: cmdLogs,
+	}
+
+	cmd.Flags().StringSliceVar(&logsServices, "services", nil, "only fetch the logs of these services")
+	cmd.Flags().IntVar(&logsTail, "tail", 0, "return N last lines of each service")
+	cmd.Flags().StringVar(&logsSearch, "search", "", "only return matching lines")
+	cmd.Flags().StringVar(&logsPeriod, "period", "", "human readable (Lucene syntax) period")
+	cmd.Flags().BoolVar(&logsFollow, "follow", false, "keep printing new lines")
+	cmd.Flags().DurationVar(&logsSince, "since", 0, "with --follow, first print the lines of this last period")
+	cmd.Flags().DurationVar(&logsInterval, "interval", 2*time.Second, "with --follow, time between two polls")
+	cmd.Flags().BoolVar(&logsNoColor, "no-color", false, "do not color service prefixes")
+
+	return cmd
+}
+
+// appLogLine is a log line of a service of an application
+type appLogLine struct {
+	Timestamp string `json:"timestamp"`
+	Service   string `json:"service"`
+	Container string `json:"container"`
+	Line      string `json:"line"`
+}
+
+type byTimestamp []appLogLine
+
+func (l byTimestamp) Len() int           { return len(l) }
+func (l byTimestamp) Less(i, j int) bool { return l[i].Timestamp < l[j].Timestamp }
+func (l byTimestamp) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+func cmdLogs(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail application logs <applicationName>. Please see sail application logs --help")
+		os.Exit(1)
+	}
+	app := args[0]
+	internal.Check(internal.CheckName(app))
+
+	if logsSince > 0 && !logsFollow {
+		internal.Exit("Error: --since can only be used with --follow\n")
+	}
+	if logsFollow && logsInterval < time.Second {
+		internal.Exit("Error: --interval must be at least 1s\n")
+	}
+
+	names := service.ListServices(app)
+	if len(logsServices) > 0 {
+		for _, name := range logsServices {
+			if !internal.StringIn(name, names) {
+				internal.Exit("Error: no such service %s in %s\n", name, app)
+			}
+		}
+		names = logsServices
+	}
+	if len(names) == 0 {
+		fmt.Fprintf(os.Stderr, "No service in %s\n", app)
+		return
+	}
+
+	params := url.Values{}
+	if logsSearch != "" {
+		params.Set("search", logsSearch)
+	}
+	if logsPeriod != "" {
+		params.Set("period", logsPeriod)
+	}
+
+	followers := make([]*service.LogFollower, len(names))
+	for i, name := range names {
+		path := fmt.Sprintf("/applications/%s/services/%s/logs", app, name)
+		followers[i] = service.NewLogFollower(path, params, logsSince)
+	}
+
+	p := newLogPrinter(names)
+	failed := make([]bool, len(names))
+
+	lines, errs := collectLogs(names, followers, func(f *service.LogFollower) ([][]string, error) {
+		return f.Start(logsTail)
+	})
+	for i, err := range errs {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not fetch the logs of %s/%s: %s\n", app, names[i], err)
+			failed[i] = true
+		}
+	}
+	p.print(lines)
+
+	if !logsFollow {
+		for _, failure := range failed {
+			if failure {
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	for {
+		time.Sleep(logsInterval)
+
+		lines, errs := collectLogs(names, followers, (*service.LogFollower).Poll)
+		for i, err := range errs {
+			if err != nil && !failed[i] {
+				fmt.Fprintf(os.Stderr, "Warning: could not fetch the logs of %s/%s: %s, retrying every %s\n", app, names[i], err, logsInterval)
+			} else if err == nil && failed[i] {
+				fmt.Fprintf(os.Stderr, "Logs of %s/%s fetched again\n", app, names[i])
+			}
+			failed[i] = err != nil
+		}
+		p.print(lines)
+	}
+}
+
+// collectLogs calls fetch on the followers of the services at the same time and
+// returns the lines merged by timestamp, and the error of each service
+func collectLogs(names []string, followers []*service.LogFollower, fetch func(*service.LogFollower) ([][]string, error)) ([]appLogLine, []error) {
+	results := make([][][]string, len(followers))
+	errs := make([]error, len(followers))
+
+	slots := make(chan struct{}, logsConcurrency)
+	var wg sync.WaitGroup
+	for i, f := range followers {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, f *service.LogFollower) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			results[i], errs[i] = fetch(f)
+		}(i, f)
+	}
+	wg.Wait()
+
+	var lines []appLogLine
+	for i, result := range results {
+		for _, line := range result {
+			lines = append(lines, appLogLine{Timestamp: line[0], Service: names[i], Container: line[1], Line: line[2]})
+		}
+	}
+	sort.Stable(byTimestamp(lines))
+
+	return lines, errs
+}
+
+// logPrinter prints log lines prefixed with their service and container
+type logPrinter struct {
+	colors map[string]string
+	width  int // of the longest prefix printed, to align lines
+}
+
+func newLogPrinter(names []string) *logPrinter {
+	p := &logPrinter{colors: make(map[string]string)}
+
+	if logsNoColor || os.Getenv("NO_COLOR") != "" || !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return p
+	}
+	for i, name := range names {
+		p.colors[name] = logsColors[i%len(logsColors)]
+	}
+	return p
+}
+
+func (p *logPrinter) print(lines []appLogLine) {
+	for _, line := range lines {
+		if width := len(line.Service) + 1 + len(line.Container); width > p.width {
+			p.width = width
+		}
+	}
+
+	for _, line := range lines {
+		if internal.Format != "pretty" {
+			data, err := json.Marshal(line)
+			internal.Check(err)
+			fmt.Println(string(data))
+			continue
+		}
+
+		prefix := fmt.Sprintf("%-*s", p.width, line.Service+"/"+line.Container)
+		if color, ok := p.colors[line.Service]; ok {
+			prefix = fmt.Sprintf("\x1b[%sm%s\x1b[0m", color, prefix)
+		}
+
+		fmt.Printf("%s %s | %s\n", line.Timestamp, prefix, line.Line)
+	}
+}
@@ -0,0 +1,129 @@
+package application
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
+	"github.com/spf13/cobra"
+)
+
+var (
+	promoteServices []string
+	promoteEnv      bool
+	promoteYes      bool
+)
+
+func promoteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Redeploy services of an application with the tags of another one: sail application promote <fromApplication> <toApplication> [--services a,b]",
+		Long: `Redeploy services of an application with the tags of another one: sail application promote <fromApplication> <toApplication> [--services a,b]
+
+Each service of <toApplication> which also exists in <fromApplication> is
+redeployed with the repository and tag of <fromApplication>. With --env,
+service environments and application environment variables added or changed
+in <fromApplication> are promoted as well. Variables are never deleted.
+
+The list of changes is displayed and, when stdin is a terminal, confirmation is
+asked before applying them, unless --yes is set.
+	"example: sail application promote myapp-staging myapp-prod --services web,worker"
+`,
+		Run: cmdPromote,
+	}
+
+	cmd.Flags().StringSliceVar(&promoteServices, "services", nil, "only promote these services")
+	cmd.Flags().BoolVar(&promoteEnv, "env", false, "promote environment changes too")
+	cmd.Flags().BoolVar(&promoteYes, "yes", false, "do not ask for confirmation")
+
+	return cmd
+}
+
+func cmdPromote(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail application promote <fromApplication> <toApplication>. Please see sail application promote --help")
+		os.Exit(1)
+	}
+	from, to := args[0], args[1]
+
+	for _, app := range args {
+		internal.Check(internal.CheckName(app))
+	}
+
+	// Build the plan
+	existing := make(map[string]bool)
+	for _, name := range service.ListServices(to) {
+		existing[name] = true
+	}
+
+	names := promoteServices
+	if len(names) == 0 {
+		names = service.ListServices(from)
+	}
+
+	var plan []service.ServiceSpec
+	var preview []string
+	for _, name := range names {
+		if !existing[name] {
+			fmt.Fprintf(os.Stderr, "Skipping %s: service does not exist in %s\n", name, to)
+			continue
+		}
+
+		src := service.FetchServiceSpec(from, name)
+		dst := service.FetchServiceSpec(to, name)
+
+		change := service.ServiceSpec{
+			Application:   to,
+			Service:       name,
+			Repository:    src.Repository,
+			RepositoryTag: src.RepositoryTag,
+		}
+		changed := src.Repository != dst.Repository || src.RepositoryTag != dst.RepositoryTag
+		line := fmt.Sprintf("  service %s: %s:%s -> %s:%s", name, dst.Repository, dst.RepositoryTag, src.Repository, src.RepositoryTag)
+
+		if promoteEnv {
+			env := internal.MergeEnv(dst.ContainerEnvironment, src.ContainerEnvironment)
+			if strings.Join(env, "\n") != strings.Join(dst.ContainerEnvironment, "\n") {
+				change.ContainerEnvironment = env
+				changed = true
+				line += " (environment changed)"
+			}
+		}
+
+		if changed {
+			plan = append(plan, change)
+			preview = append(preview, line)
+		}
+	}
+
+	var envChanges []envChange
+	if promoteEnv {
+		for _, change := range envDiff(envList(to), envList(from)) {
+			if change.op != "-" {
+				envChanges = append(envChanges, change)
+				preview = append(preview, fmt.Sprintf("  env %s %s", change.op, change.key))
+			}
+		}
+	}
+
+	if len(preview) == 0 {
+		fmt.Fprintf(os.Stderr, "Nothing to promote from %s to %s\n", from, to)
+		return
+	}
+
+	// Preview and confirm, the changes are the impact
+	fmt.Fprintf(os.Stderr, "Changes to apply on %s:\n%s\n", to, strings.Join(preview, "\n"))
+	if !internal.ConfirmImpact(promoteYes, "Proceed?", func() []string { return nil }) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(1)
+	}
+
+	// Apply
+	envApply(to, envChanges)
+	for _, spec := range plan {
+		fmt.Fprintf(os.Stderr, "Redeploying %s/%s...\n", spec.Application, spec.Service)
+		service.RedeploySpec(spec)
+	}
+}
@@ -0,0 +1,295 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/network"
+	"github.com/runabove/sail/service"
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreConflict string
+	restoreDryRun   bool
+)
+
+func restoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Recreate an application from an archive: sail application restore <file.tar.gz> [<applicationName>] [--conflict fail|skip|overwrite] [--dry-run]",
+		Long: `Recreate an application from an archive: sail application restore <file.tar.gz> [<applicationName>] [--conflict fail|skip|overwrite] [--dry-run]
+
+Restores an archive written by 'sail application backup' into <applicationName>,
+by default the application it was saved from. Repositories, environment
+variables, networks, services (in link order), routes and webhooks are created
+when missing.
+
+A conflict is an existing resource with a different definition:
+	fail       nothing is changed if there is any conflict (default)
+	skip       existing resources are kept as is
+	overwrite  existing resources are replaced: environment variables are set,
+	           networks are deleted and created again, services are redeployed
+	           with their whole definition and repositories are registered
+	           again
+
+Only external repositories can be restored, images of hosted repositories must
+be pushed again. The list of changes is displayed first, --dry-run stops there.
+	"example: sail application restore myapp.tar.gz myapp-restored --conflict skip"
+`,
+		Run: cmdRestore,
+	}
+
+	cmd.Flags().StringVar(&restoreConflict, "conflict", "fail", "conflict strategy, one of 'fail', 'skip' and 'overwrite'")
+	cmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "only display the changes")
+
+	return cmd
+}
+
+// restoreStep is a single change of a restore
+type restoreStep struct {
+	kind   string
+	name   string
+	action string // one of 'create', 'update', 'overwrite', 'skip', 'conflict'
+	note   string
+	apply  func()
+}
+
+func cmdRestore(cmd *cobra.Command, args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail application restore <file.tar.gz> [<applicationName>]. Please see sail application restore --help")
+		os.Exit(1)
+	}
+
+	switch restoreConflict {
+	case "fail", "skip", "overwrite":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: Invalid conflict strategy '%s'. Must be one of 'fail', 'skip' and 'overwrite'\n", restoreConflict)
+		os.Exit(1)
+	}
+
+	backup, err := readBackup(args[0])
+	internal.Check(err)
+
+	app := backup.Manifest.Application
+	if len(args) == 2 {
+		app = args[1]
+	}
+	internal.Check(internal.CheckName(app))
+
+	fmt.Fprintf(os.Stderr, "Restoring %s (saved from %s on %s) to %s\n", args[0], backup.Manifest.Application, backup.Manifest.Date, app)
+
+	steps, err := restorePlan(backup, app)
+	internal.Check(err)
+
+	if len(steps) == 0 {
+		fmt.Fprintf(os.Stderr, "Nothing to restore, %s is up to date\n", app)
+		return
+	}
+
+	conflicts := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tACTION\tNOTE")
+	for _, step := range steps {
+		if step.action == "conflict" {
+			conflicts++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", step.kind, step.name, step.action, orDash(step.note))
+	}
+	w.Flush()
+
+	if conflicts > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d conflict(s) with existing resources of %s. Use --conflict skip or --conflict overwrite\n", conflicts, app)
+		os.Exit(1)
+	}
+	if restoreDryRun {
+		return
+	}
+
+	for _, step := range steps {
+		if step.apply != nil {
+			step.apply()
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Restored %s\n", app)
+}
+
+// restorePlan compares an archive to the current state of app and returns the changes to apply
+func restorePlan(backup appBackup, app string) ([]restoreStep, error) {
+	var steps []restoreStep
+
+	// conflict returns the step for an existing resource with a different definition
+	conflict := func(kind, name, note string, overwrite func()) restoreStep {
+		switch restoreConflict {
+		case "skip":
+			return restoreStep{kind: kind, name: name, action: "skip", note: note}
+		case "overwrite":
+			return restoreStep{kind: kind, name: name, action: "overwrite", note: note, apply: overwrite}
+		}
+		return restoreStep{kind: kind, name: name, action: "conflict", note: note}
+	}
+
+	// Repositories
+	existingRepositories := make(map[string]backupRepository)
+	for _, repository := range fetchRepositories(app) {
+		existingRepositories[repository.Name] = repository
+	}
+	for _, r := range backup.Repositories {
+		r := r
+		current, exists := existingRepositories[r.Name]
+		switch {
+		case exists && current.Type == r.Type && current.Source == r.Source:
+			continue
+		case r.Type != "external":
+			steps = append(steps, restoreStep{kind: "repository", name: r.Name, action: "skip", note: "hosted repository, images must be pushed again"})
+		case exists:
+			steps = append(steps, conflict("repository", r.Name, fmt.Sprintf("source %s -> %s", orDash(current.Source), r.Source), func() {
+				internal.DeleteWantJSON(fmt.Sprintf("/repositories/%s/%s", app, r.Name))
+				registerRepository(app, r)
+			}))
+		default:
+			steps = append(steps, restoreStep{kind: "repository", name: r.Name, action: "create", apply: func() {
+				registerRepository(app, r)
+			}})
+		}
+	}
+
+	// Environment
+	for _, change := range envDiff(envList(app), backup.Env) {
+		change := change
+		apply := func() {
+			envSet(app, change.key, change.value)
+			fmt.Fprintf(os.Stderr, "Set environment variable %s\n", change.key)
+		}
+
+		switch change.op {
+		case "+":
+			steps = append(steps, restoreStep{kind: "env", name: change.key, action: "create", apply: apply})
+		case "~":
+			steps = append(steps, conflict("env", change.key, "value differs", apply))
+		}
+	}
+
+	// Networks
+	existingNetworks := make(map[string]bool)
+	for _, name := range network.List(app) {
+		existingNetworks[name] = true
+	}
+	for _, n := range backup.Networks {
+		n := n
+		name := n.Name
+		if name == backup.Manifest.Application {
+			name = app
+		}
+
+		if !existingNetworks[name] {
+			steps = append(steps, restoreStep{kind: "network", name: name, action: "create", note: n.Subnet, apply: func() {
+				network.Create(app, name, n.Subnet, n.Ranges)
+				fmt.Fprintf(os.Stderr, "Created network %s/%s\n", app, name)
+			}})
+			continue
+		}
+
+		subnet, ranges := network.Get(app, name)
+		if subnet != n.Subnet {
+			steps = append(steps, conflict("network", name, fmt.Sprintf("subnet %s -> %s", subnet, n.Subnet), func() {
+				network.Delete(app, name)
+				network.Create(app, name, n.Subnet, n.Ranges)
+				fmt.Fprintf(os.Stderr, "Created network %s/%s\n", app, name)
+			}))
+			continue
+		}
+
+		if missing := missingStrings(ranges, n.Ranges); len(missing) > 0 {
+			steps = append(steps, restoreStep{kind: "network", name: name, action: "update", note: "add ranges " + strings.Join(missing, ","), apply: func() {
+				network.AddRanges(app, name, missing)
+				fmt.Fprintf(os.Stderr, "Added ranges to network %s/%s\n", app, name)
+			}})
+		}
+	}
+
+	// Services, in link order
+	specs := make(map[string]service.ServiceSpec)
+	for _, spec := range backup.Services {
+		specs[spec.Service] = service.RewriteSpec(spec, app, spec.Service)
+	}
+	order, err := service.LinkOrder(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	existingServices := make(map[string]bool)
+	for _, name := range service.ListServices(app) {
+		existingServices[name] = true
+	}
+	for _, name := range order {
+		spec := specs[name]
+		if existingServices[name] {
+			if service.SameDefinition(service.FetchServiceSpec(app, name), spec) {
+				continue
+			}
+			steps = append(steps, conflict("service", name, fmt.Sprintf("%s:%s", spec.Repository, spec.RepositoryTag), func() {
+				fmt.Fprintf(os.Stderr, "Redeploying %s/%s...\n", app, spec.Service)
+				service.ReplaceSpec(spec)
+			}))
+			continue
+		}
+		steps = append(steps, restoreStep{kind: "service", name: name, action: "create", note: fmt.Sprintf("%s:%s", spec.Repository, spec.RepositoryTag), apply: func() {
+			service.CreateService(spec)
+		}})
+	}
+
+	// Routes
+	existingRoutes := make(map[backupRoute]bool)
+	for _, route := range fetchRoutes(app) {
+		existingRoutes[route] = true
+	}
+	for _, route := range backup.Routes {
+		route := route
+		if existingRoutes[route] {
+			continue
+		}
+		name := fmt.Sprintf("%s %s%s", route.Method, route.Domain, route.Pattern)
+		steps = append(steps, restoreStep{kind: "route", name: name, action: "create", note: "service " + route.Service, apply: func() {
+			internal.Check(service.AttachRoute(app, route.Service, route.Domain, route.Pattern, route.Method))
+		}})
+	}
+
+	// Webhooks
+	for _, url := range missingStrings(fetchWebhooks(app), backup.Webhooks) {
+		url := url
+		steps = append(steps, restoreStep{kind: "webhook", name: url, action: "create", apply: func() {
+			webhookAdd(app, url)
+		}})
+	}
+
+	return steps, nil
+}
+
+// registerRepository registers an external repository
+func registerRepository(app string, r backupRepository) {
+	body, err := json.Marshal(map[string]string{"externalRepositoryName": r.Source})
+	internal.Check(err)
+
+	internal.PostBodyWantJSON(fmt.Sprintf("/repositories/%s/%s", app, r.Name), body)
+	fmt.Fprintf(os.Stderr, "Registered repository %s/%s\n", app, r.Name)
+}
+
+// missingStrings returns the items of wanted which are not in current
+func missingStrings(current, wanted []string) []string {
+	var missing []string
+	existing := make(map[string]bool)
+	for _, s := range current {
+		existing[s] = true
+	}
+	for _, s := range wanted {
+		if !existing[s] {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
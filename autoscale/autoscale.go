@@ -0,0 +1,25 @@
+package autoscale
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	Cmd.AddCommand(runCmd())
+	Cmd.AddCommand(scheduleCmd())
+}
+
+// Cmd autoscale
+var Cmd = &cobra.Command{
+	Use:   "autoscale",
+	Short: "Automatic scaling of services: sail autoscale --help",
+	Long:  `Automatic scaling of services: sail autoscale <command>`,
+}
+
+// logf prints a timestamped line, autoscale commands being long running
+func logf(format string, args ...interface{}) {
+	fmt.Printf("%s %s\n", time.Now().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,221 @@
+package autoscale
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricSource returns the current value of the metric an autoscaler follows
+type metricSource interface {
+	value() (float64, error)
+}
+
+// newMetricSource returns a source querying a metrics backend. token, when set,
+// is sent as basic auth credentials if of form <id>:<secret>, as a bearer token
+// otherwise.
+func newMetricSource(backend, endpoint, query, token string, window time.Duration) (metricSource, error) {
+	if _, err := url.Parse(endpoint); err != nil || endpoint == "" {
+		return nil, fmt.Errorf("Invalid metrics endpoint '%s'", endpoint)
+	}
+	if query == "" {
+		return nil, fmt.Errorf("A metrics query is required")
+	}
+
+	c := metricsClient{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		token:    token,
+		http:     &http.Client{Timeout: 10 * time.Second},
+	}
+
+	switch strings.ToLower(backend) {
+	case "prometheus":
+		return prometheusSource{c, query}, nil
+	case "opentsdb":
+		return opentsdbSource{c, query, window}, nil
+	}
+	return nil, fmt.Errorf("Invalid metrics backend '%s'. Must be one of 'prometheus' and 'opentsdb'", backend)
+}
+
+type metricsClient struct {
+	endpoint string
+	token    string
+	http     *http.Client
+}
+
+// request returns the authenticated GET request of path
+func (c metricsClient) request(path string, params url.Values) (*http.Request, error) {
+	req, err := http.NewRequest("GET", c.endpoint+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if i := strings.Index(c.token, ":"); i >= 0 {
+		req.SetBasicAuth(c.token[:i], c.token[i+1:])
+	} else if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// get returns the body of path, which must answer with a 200 status
+func (c metricsClient) get(path string, params url.Values) ([]byte, error) {
+	req, err := c.request(path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics backend returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// prometheusSource runs an instant query against a Prometheus compatible API.
+// Vector results are averaged.
+type prometheusSource struct {
+	client metricsClient
+	query  string
+}
+
+func (s prometheusSource) value() (float64, error) {
+	body, err := s.client.get("/api/v1/query", url.Values{"query": {s.query}})
+	if err != nil {
+		return 0, err
+	}
+	return prometheusValue(body)
+}
+
+// prometheusValue returns the value of the answer to an instant query
+func prometheusValue(body []byte) (float64, error) {
+	var resp struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Status != "success" {
+		return 0, fmt.Errorf("query failed: %s", resp.Error)
+	}
+
+	var samples [][]interface{}
+	switch resp.Data.ResultType {
+	case "scalar":
+		var sample []interface{}
+		if err := json.Unmarshal(resp.Data.Result, &sample); err != nil {
+			return 0, err
+		}
+		samples = append(samples, sample)
+	case "vector":
+		var series []struct {
+			Value []interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(resp.Data.Result, &series); err != nil {
+			return 0, err
+		}
+		for _, serie := range series {
+			samples = append(samples, serie.Value)
+		}
+	default:
+		return 0, fmt.Errorf("unsupported result type '%s', the query must return a scalar or an instant vector", resp.Data.ResultType)
+	}
+
+	var values []float64
+	for _, sample := range samples {
+		if len(sample) != 2 {
+			return 0, fmt.Errorf("invalid sample %v", sample)
+		}
+		v, err := strconv.ParseFloat(fmt.Sprint(sample[1]), 64)
+		if err != nil {
+			return 0, err
+		}
+		values = append(values, v)
+	}
+	return average(values)
+}
+
+// opentsdbSource queries an OpenTSDB API for the last data point of each time
+// serie over window, and averages them
+type opentsdbSource struct {
+	client metricsClient
+	query  string
+	window time.Duration
+}
+
+func (s opentsdbSource) value() (float64, error) {
+	body, err := s.client.get("/api/query", s.params())
+	if err != nil {
+		return 0, err
+	}
+	return opentsdbValue(body)
+}
+
+func (s opentsdbSource) params() url.Values {
+	return url.Values{
+		"start": {fmt.Sprintf("%ds-ago", int(s.window.Seconds()))},
+		"m":     {s.query},
+	}
+}
+
+// opentsdbValue returns the average of the last data points of the series
+// answered to a query
+func opentsdbValue(body []byte) (float64, error) {
+	var series []struct {
+		Dps map[string]float64 `json:"dps"`
+	}
+	if err := json.Unmarshal(body, &series); err != nil {
+		return 0, err
+	}
+
+	var values []float64
+	for _, serie := range series {
+		var last int64 = -1
+		var value float64
+		for ts, v := range serie.Dps {
+			t, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid timestamp '%s'", ts)
+			}
+			if t > last {
+				last, value = t, v
+			}
+		}
+		if last >= 0 {
+			values = append(values, value)
+		}
+	}
+	return average(values)
+}
+
+func average(values []float64) (float64, error) {
+	if len(values) == 0 {
+		return 0, fmt.Errorf("no data point")
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values)), nil
+}
@@ -0,0 +1,95 @@
+package autoscale
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPrometheusValue(t *testing.T) {
+	tests := []struct {
+		body  string
+		value float64
+		err   bool
+	}{
+		{`{"status":"success","data":{"resultType":"scalar","result":[1500000000,"0.7"]}}`, 0.7, false},
+		{`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1500000000,"0.5"]},{"metric":{},"value":[1500000000,"1"]}]}}`, 0.75, false},
+		{`{"status":"success","data":{"resultType":"vector","result":[]}}`, 0, true},
+		{`{"status":"success","data":{"resultType":"matrix","result":[]}}`, 0, true},
+		{`{"status":"error","error":"parse error"}`, 0, true},
+		{`{"status":"success","data":{"resultType":"scalar","result":[1500000000,"NaN?"]}}`, 0, true},
+		{`not json`, 0, true},
+	}
+
+	for _, test := range tests {
+		value, err := prometheusValue([]byte(test.body))
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %g", test.body, value)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %s", test.body, err)
+		} else if value != test.value {
+			t.Errorf("%s: got %g, want %g", test.body, value, test.value)
+		}
+	}
+}
+
+func TestOpentsdbValue(t *testing.T) {
+	tests := []struct {
+		body  string
+		value float64
+		err   bool
+	}{
+		// Last data point of each serie, averaged
+		{`[{"dps":{"1500000000":1,"1500000060":3,"1500000030":9}},{"dps":{"1500000000":5}}]`, 4, false},
+		{`[{"dps":{}}]`, 0, true},
+		{`[]`, 0, true},
+		{`[{"dps":{"yesterday":1}}]`, 0, true},
+	}
+
+	for _, test := range tests {
+		value, err := opentsdbValue([]byte(test.body))
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %g", test.body, value)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %s", test.body, err)
+		} else if value != test.value {
+			t.Errorf("%s: got %g, want %g", test.body, value, test.value)
+		}
+	}
+}
+
+func TestMetricsRequest(t *testing.T) {
+	source, err := newMetricSource("opentsdb", "http://tsdb.example/", "avg:cpu.usage", "token", 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := source.(opentsdbSource)
+
+	req, err := s.client.request("/api/query", s.params())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.Host != "tsdb.example" || req.URL.Path != "/api/query" {
+		t.Errorf("request sent to %s, want http://tsdb.example/api/query", req.URL)
+	}
+	query := req.URL.Query()
+	if query.Get("m") != "avg:cpu.usage" || query.Get("start") != "300s-ago" {
+		t.Errorf("query sent is %s, want m=avg:cpu.usage and start=300s-ago", req.URL.RawQuery)
+	}
+	if auth := req.Header.Get("Authorization"); auth != "Bearer token" {
+		t.Errorf("Authorization sent is '%s', want 'Bearer token'", auth)
+	}
+
+	c := metricsClient{endpoint: "http://prometheus.example", token: "id:secret", http: http.DefaultClient}
+	req, err = c.request("/api/v1/query", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, password, ok := req.BasicAuth(); !ok || user != "id" || password != "secret" {
+		t.Errorf("basic auth sent is %s:%s, want id:secret", user, password)
+	}
+}
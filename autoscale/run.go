@@ -0,0 +1,198 @@
+package autoscale
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
+)
+
+var (
+	runBackend           string
+	runEndpoint          string
+	runQuery             string
+	runToken             string
+	runWindow            time.Duration
+	runTarget            float64
+	runTolerance         float64
+	runMin               int
+	runMax               int
+	runInterval          time.Duration
+	runScaleUpCooldown   time.Duration
+	runScaleDownCooldown time.Duration
+	runDryRun            bool
+)
+
+func runCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Scale a service on a metric: sail autoscale run [<applicationName>/]<serviceId> --endpoint <url> --query <query> --target <value> --max <number>",
+		Long: `Scale a service on a metric: sail autoscale run [<applicationName>/]<serviceId> --endpoint <url> --query <query> --target <value> --max <number>
+
+Runs until interrupted. Every --interval, the metric is read from a Prometheus
+or OpenTSDB compatible backend and the service is scaled so that the metric, an
+average per container such as CPU usage, gets back to --target:
+
+  containers = ceil(current containers * metric / target)
+
+No scaling happens while the metric is within --tolerance of the target, nor
+before --scale-up-cooldown or --scale-down-cooldown elapsed since the last
+scaling. The number of containers is kept between --min and --max.
+
+With prometheus, --query is an expression whose instant value is used. With
+opentsdb, it is a metric query such as 'avg:cpu.usage{app=my-app}' whose last
+data point over --window is used. Series are averaged.
+
+--token, or SAIL_METRICS_TOKEN, holds the credentials returned by 'sail metric
+token create', as <id>:<secret> for basic auth, or a bearer token.
+	"example: sail autoscale run my-app/web --backend prometheus --endpoint http://localhost:9090 --query 'avg(cpu)' --target 0.7 --min 2 --max 10"
+`,
+		Run: cmdRun,
+	}
+
+	cmd.Flags().StringVar(&runBackend, "backend", "opentsdb", "metrics backend: 'opentsdb' or 'prometheus'")
+	cmd.Flags().StringVar(&runEndpoint, "endpoint", "", "URL of the metrics backend")
+	cmd.Flags().StringVar(&runQuery, "query", "", "query returning the metric to follow")
+	cmd.Flags().StringVar(&runToken, "token", os.Getenv("SAIL_METRICS_TOKEN"), "metrics token, <id>:<secret> or bearer token")
+	cmd.Flags().DurationVar(&runWindow, "window", 5*time.Minute, "opentsdb: period to look for the last data point in")
+	cmd.Flags().Float64Var(&runTarget, "target", 0, "value of the metric to maintain")
+	cmd.Flags().Float64Var(&runTolerance, "tolerance", 0.1, "ratio around the target within which no scaling happens")
+	cmd.Flags().IntVar(&runMin, "min", 1, "minimum number of containers")
+	cmd.Flags().IntVar(&runMax, "max", 0, "maximum number of containers")
+	cmd.Flags().DurationVar(&runInterval, "interval", 30*time.Second, "delay between 2 evaluations")
+	cmd.Flags().DurationVar(&runScaleUpCooldown, "scale-up-cooldown", time.Minute, "minimum delay after a scaling before scaling up")
+	cmd.Flags().DurationVar(&runScaleDownCooldown, "scale-down-cooldown", 5*time.Minute, "minimum delay after a scaling before scaling down")
+	cmd.Flags().BoolVar(&runDryRun, "dry-run", false, "log decisions without scaling")
+
+	return cmd
+}
+
+func cmdRun(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail autoscale run [<applicationName>/]<serviceId> --endpoint <url> --query <query> --target <value> --max <number>. Please see sail autoscale run --help")
+		os.Exit(1)
+	}
+
+	host, app, svc, _, err := internal.ParseResourceName(args[0])
+	internal.Check(err)
+
+	if !internal.CheckHostConsistent(host) {
+		fmt.Fprintf(os.Stderr, "Error: Invalid Host %s for endpoint %s\n", host, internal.Host)
+		os.Exit(1)
+	}
+
+	if runTarget <= 0 {
+		internal.Exit("Error: --target must be greater than 0\n")
+	}
+	if runTolerance < 0 {
+		internal.Exit("Error: --tolerance must not be negative\n")
+	}
+	if runMin < 1 || runMax < runMin {
+		internal.Exit("Error: --min must be at least 1 and --max at least --min\n")
+	}
+	if runInterval <= 0 {
+		internal.Exit("Error: --interval must be greater than 0\n")
+	}
+
+	source, err := newMetricSource(runBackend, runEndpoint, runQuery, runToken, runWindow)
+	internal.Check(err)
+
+	a := autoscaler{
+		app:               app,
+		service:           svc,
+		source:            source,
+		target:            runTarget,
+		tolerance:         runTolerance,
+		min:               runMin,
+		max:               runMax,
+		scaleUpCooldown:   runScaleUpCooldown,
+		scaleDownCooldown: runScaleDownCooldown,
+	}
+
+	logf("Autoscaling %s/%s between %d and %d containers, target %g", app, svc, runMin, runMax, runTarget)
+	for {
+		a.evaluate(time.Now())
+		time.Sleep(runInterval)
+	}
+}
+
+type autoscaler struct {
+	app               string
+	service           string
+	source            metricSource
+	target            float64
+	tolerance         float64
+	min, max          int
+	scaleUpCooldown   time.Duration
+	scaleDownCooldown time.Duration
+	lastScale         time.Time
+}
+
+// desired returns the number of containers bringing value to the target
+func (a *autoscaler) desired(current int, value float64) int {
+	want := current
+	if ratio := value / a.target; math.Abs(ratio-1) > a.tolerance {
+		want = int(math.Ceil(float64(current) * ratio))
+	}
+
+	if want < a.min {
+		want = a.min
+	} else if want > a.max {
+		want = a.max
+	}
+	return want
+}
+
+// cooldownLeft returns how long to wait before scaling from current to want
+// containers, 0 if it may happen now
+func (a *autoscaler) cooldownLeft(now time.Time, current, want int) time.Duration {
+	cooldown := a.scaleDownCooldown
+	if want > current {
+		cooldown = a.scaleUpCooldown
+	}
+	if since := now.Sub(a.lastScale); since < cooldown {
+		return cooldown - since
+	}
+	return 0
+}
+
+func (a *autoscaler) evaluate(now time.Time) {
+	name := fmt.Sprintf("%s/%s", a.app, a.service)
+
+	value, err := a.source.value()
+	if err != nil {
+		logf("%s: Error: could not read metric: %s", name, err)
+		return
+	}
+
+	current, err := service.ContainerNumber(a.app, a.service)
+	if err != nil {
+		logf("%s: Error: %s", name, err)
+		return
+	}
+
+	want := a.desired(current, value)
+	if want == current {
+		return
+	}
+
+	if wait := a.cooldownLeft(now, current, want); wait > 0 {
+		logf("%s: metric %g, target %g: would scale from %d to %d containers, cooling down for %s", name, value, a.target, current, want, wait/time.Second*time.Second)
+		return
+	}
+
+	logf("%s: metric %g, target %g: scaling from %d to %d containers", name, value, a.target, current, want)
+	if runDryRun {
+		return
+	}
+	if err := service.ScaleService(a.app, a.service, want); err != nil {
+		logf("%s: Error: %s", name, err)
+		return
+	}
+	a.lastScale = now
+}
@@ -0,0 +1,70 @@
+package autoscale
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDesired(t *testing.T) {
+	a := autoscaler{target: 0.5, tolerance: 0.1, min: 2, max: 10}
+
+	tests := []struct {
+		current int
+		value   float64
+		want    int
+	}{
+		// Within the tolerance band
+		{4, 0.5, 4},
+		{4, 0.54, 4},
+		{4, 0.46, 4},
+		// Out of the band
+		{4, 0.75, 6},
+		{4, 0.56, 5},
+		{4, 0.25, 2},
+		{5, 0.4, 4},
+		// Clamped to min and max
+		{4, 0.1, 2},
+		{4, 0, 2},
+		{4, 5, 10},
+		{12, 0.5, 10},
+		{1, 0.5, 2},
+	}
+
+	for _, test := range tests {
+		if got := a.desired(test.current, test.value); got != test.want {
+			t.Errorf("desired(%d, %g) = %d, want %d", test.current, test.value, got, test.want)
+		}
+	}
+}
+
+func TestCooldownLeft(t *testing.T) {
+	now := time.Now()
+	a := autoscaler{
+		scaleUpCooldown:   time.Minute,
+		scaleDownCooldown: 5 * time.Minute,
+	}
+
+	// Never scaled
+	if got := a.cooldownLeft(now, 2, 4); got != 0 {
+		t.Errorf("cooldownLeft without previous scaling = %s, want 0", got)
+	}
+
+	a.lastScale = now.Add(-2 * time.Minute)
+	tests := []struct {
+		current, want int
+		left          time.Duration
+	}{
+		{2, 4, 0},
+		{4, 2, 3 * time.Minute},
+	}
+	for _, test := range tests {
+		if got := a.cooldownLeft(now, test.current, test.want); got != test.left {
+			t.Errorf("cooldownLeft(%d -> %d) = %s, want %s", test.current, test.want, got, test.left)
+		}
+	}
+
+	a.lastScale = now.Add(-30 * time.Second)
+	if got := a.cooldownLeft(now, 2, 4); got != 30*time.Second {
+		t.Errorf("cooldownLeft(2 -> 4) = %s, want 30s", got)
+	}
+}
@@ -0,0 +1,322 @@
+package autoscale
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
+)
+
+var scheduleFile string
+
+func scheduleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Scale services on a schedule: sail autoscale schedule --file <rules.yml>",
+		Long: `Scale services on a schedule: sail autoscale schedule --file <rules.yml>
+
+Runs until interrupted and scales services according to time based rules.
+The rules are checked on start-up, then every minute. The first rule matching
+the current time gives the number of containers of a service, 'default' applies
+when none does. Services already at the right number are left untouched.
+
+  timezone: Europe/Paris          # default to local time
+  services:
+    my-app/web:
+      default: 2                  # optional, leave as is when omitted
+      rules:
+        - days: mon-fri           # '*', 'weekdays', 'weekends', 'sat,sun', 1-5...
+          hours: "08:00-20:00"    # optional, may span midnight: "22:00-06:00"
+          containers: 6
+`,
+		Run: cmdSchedule,
+	}
+
+	cmd.Flags().StringVar(&scheduleFile, "file", "", "YAML file holding the scaling rules")
+
+	return cmd
+}
+
+func cmdSchedule(cmd *cobra.Command, args []string) {
+	if len(args) != 0 || scheduleFile == "" {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail autoscale schedule --file <rules.yml>. Please see sail autoscale schedule --help")
+		os.Exit(1)
+	}
+
+	s, err := loadSchedule(scheduleFile)
+	internal.Check(err)
+
+	logf("Loaded %d service(s) from %s, timezone %s", len(s.services), scheduleFile, s.location)
+
+	// Reconcile right away: rules may have changed while not running
+	for {
+		s.reconcile(time.Now())
+
+		now := time.Now()
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+	}
+}
+
+// schedule file format
+type scheduleConfig struct {
+	Timezone string                           `json:"timezone"`
+	Services map[string]scheduleServiceConfig `json:"services"`
+}
+
+type scheduleServiceConfig struct {
+	Default *int                 `json:"default"`
+	Rules   []scheduleRuleConfig `json:"rules"`
+}
+
+type scheduleRuleConfig struct {
+	Days       string `json:"days"`
+	Hours      string `json:"hours"`
+	Containers *int   `json:"containers"`
+}
+
+type schedule struct {
+	location *time.Location
+	services []serviceSchedule
+}
+
+type serviceSchedule struct {
+	app      string
+	service  string
+	fallback *int
+	rules    []scheduleRule
+}
+
+type scheduleRule struct {
+	name       string
+	days       [7]bool
+	allDay     bool
+	from, to   int // minutes since midnight
+	containers int
+}
+
+func loadSchedule(path string) (*schedule, error) {
+	payload, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config scheduleConfig
+	if err := yaml.Unmarshal(payload, &config); err != nil {
+		return nil, fmt.Errorf("Invalid rules file %s: %s", path, err)
+	}
+
+	s := &schedule{location: time.Local}
+	if config.Timezone != "" {
+		if s.location, err = time.LoadLocation(config.Timezone); err != nil {
+			return nil, fmt.Errorf("Invalid timezone '%s': %s", config.Timezone, err)
+		}
+	}
+
+	if len(config.Services) == 0 {
+		return nil, fmt.Errorf("No service in rules file %s", path)
+	}
+
+	names := make([]string, 0, len(config.Services))
+	for name := range config.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		host, app, svc, _, err := internal.ParseResourceName(name)
+		if err != nil {
+			return nil, err
+		}
+		if !internal.CheckHostConsistent(host) {
+			return nil, fmt.Errorf("Invalid Host %s for endpoint %s", host, internal.Host)
+		}
+
+		config := config.Services[name]
+		ss := serviceSchedule{app: app, service: svc, fallback: config.Default}
+		if ss.fallback != nil && *ss.fallback < 0 {
+			return nil, fmt.Errorf("%s: default must not be negative", name)
+		}
+		for i, rc := range config.Rules {
+			rule, err := parseScheduleRule(rc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: rule %d: %s", name, i+1, err)
+			}
+			ss.rules = append(ss.rules, rule)
+		}
+		s.services = append(s.services, ss)
+	}
+
+	return s, nil
+}
+
+func parseScheduleRule(config scheduleRuleConfig) (scheduleRule, error) {
+	var rule scheduleRule
+	var err error
+
+	if config.Containers == nil || *config.Containers < 0 {
+		return rule, fmt.Errorf("'containers' must be set to 0 or more")
+	}
+	rule.containers = *config.Containers
+
+	if rule.days, err = parseDays(config.Days); err != nil {
+		return rule, err
+	}
+
+	rule.name = config.Days
+	if rule.name == "" {
+		rule.name = "*"
+	}
+
+	if config.Hours == "" {
+		rule.allDay = true
+		return rule, nil
+	}
+
+	bounds := strings.Split(config.Hours, "-")
+	if len(bounds) != 2 {
+		return rule, fmt.Errorf("Invalid hours '%s', should be of form HH:MM-HH:MM", config.Hours)
+	}
+	if rule.from, err = parseClock(bounds[0]); err != nil {
+		return rule, err
+	}
+	if rule.to, err = parseClock(bounds[1]); err != nil {
+		return rule, err
+	}
+	if rule.from == rule.to {
+		return rule, fmt.Errorf("Invalid hours '%s', empty range", config.Hours)
+	}
+	rule.name += " " + config.Hours
+
+	return rule, nil
+}
+
+var dayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// parseDays parses a cron like list of days of week: names or numbers, 0 and 7
+// being sunday, and ranges thereof
+func parseDays(spec string) ([7]bool, error) {
+	var days [7]bool
+
+	switch strings.ToLower(strings.TrimSpace(spec)) {
+	case "", "*", "daily":
+		spec = "sun-sat"
+	case "weekdays":
+		spec = "mon-fri"
+	case "weekends":
+		spec = "sat,sun"
+	}
+
+	for _, item := range strings.Split(spec, ",") {
+		bounds := strings.Split(strings.TrimSpace(item), "-")
+		if len(bounds) > 2 {
+			return days, fmt.Errorf("Invalid days '%s'", spec)
+		}
+
+		first, err := parseDay(bounds[0])
+		if err != nil {
+			return days, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = parseDay(bounds[1]); err != nil {
+				return days, err
+			}
+		}
+
+		// Ranges may wrap around the week, as in fri-mon
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+
+	return days, nil
+}
+
+func parseDay(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 7 {
+		return n % 7, nil
+	}
+	for i, name := range dayNames {
+		if len(s) >= 3 && strings.HasPrefix(name, s[:3]) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("Invalid day '%s'", s)
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid time '%s', should be of form HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// match tells whether t, in the schedule timezone, is covered by the rule. A range
+// spanning midnight belongs to the day it starts.
+func (r scheduleRule) match(t time.Time) bool {
+	day := int(t.Weekday())
+	if r.allDay {
+		return r.days[day]
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	if r.from < r.to {
+		return r.days[day] && minute >= r.from && minute < r.to
+	}
+	return r.days[day] && minute >= r.from || r.days[(day+6)%7] && minute < r.to
+}
+
+// desired returns the number of containers wanted at t and the reason why.
+// ok is false when no rule applies.
+func (s serviceSchedule) desired(t time.Time) (containers int, reason string, ok bool) {
+	for _, rule := range s.rules {
+		if rule.match(t) {
+			return rule.containers, "rule " + rule.name, true
+		}
+	}
+	if s.fallback != nil {
+		return *s.fallback, "default", true
+	}
+	return 0, "", false
+}
+
+// reconcile scales every service whose number of containers differs from its schedule
+func (s *schedule) reconcile(now time.Time) {
+	now = now.In(s.location)
+
+	for _, ss := range s.services {
+		want, reason, ok := ss.desired(now)
+		if !ok {
+			continue
+		}
+
+		current, err := service.ContainerNumber(ss.app, ss.service)
+		if err != nil {
+			logf("%s/%s: Error: %s", ss.app, ss.service, err)
+			continue
+		}
+		if current == want {
+			continue
+		}
+
+		logf("%s/%s: scaling from %d to %d containers (%s)", ss.app, ss.service, current, want, reason)
+		if err := service.ScaleService(ss.app, ss.service, want); err != nil {
+			logf("%s/%s: Error: %s", ss.app, ss.service, err)
+		}
+	}
+}
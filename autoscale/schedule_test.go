@@ -0,0 +1,135 @@
+package autoscale
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDays(t *testing.T) {
+	tests := []struct {
+		spec string
+		days string // sun to sat, 'x' for the days included
+		err  bool
+	}{
+		{"", "xxxxxxx", false},
+		{"*", "xxxxxxx", false},
+		{"daily", "xxxxxxx", false},
+		{"weekdays", ".xxxxx.", false},
+		{"weekends", "x.....x", false},
+		{"mon-fri", ".xxxxx.", false},
+		{"1-5", ".xxxxx.", false},
+		{"Monday, Wed", ".x.x...", false},
+		{"sat,sun", "x.....x", false},
+		{"0", "x......", false},
+		{"7", "x......", false},
+		// Ranges wrapping around the week
+		{"fri-mon", "xx...xx", false},
+		{"6-1", "xx....x", false},
+		{"sun-sat", "xxxxxxx", false},
+		{"wed-wed", "...x...", false},
+		{"mon-", "", true},
+		{"mon-wed-fri", "", true},
+		{"8", "", true},
+		{"mo", "", true},
+		{"funday", "", true},
+	}
+
+	for _, test := range tests {
+		days, err := parseDays(test.spec)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", test.spec, err)
+			continue
+		}
+
+		got := ""
+		for _, day := range days {
+			if day {
+				got += "x"
+			} else {
+				got += "."
+			}
+		}
+		if got != test.days {
+			t.Errorf("%q: got %s, want %s", test.spec, got, test.days)
+		}
+	}
+}
+
+func TestScheduleRuleMatch(t *testing.T) {
+	six := 6
+
+	tests := []struct {
+		days, hours string
+		time        string // 2016-01-01 is a friday
+		match       bool
+	}{
+		{"mon-fri", "08:00-20:00", "2016-01-01 08:00", true},
+		{"mon-fri", "08:00-20:00", "2016-01-01 19:59", true},
+		{"mon-fri", "08:00-20:00", "2016-01-01 20:00", false},
+		{"mon-fri", "08:00-20:00", "2016-01-01 07:59", false},
+		{"mon-fri", "08:00-20:00", "2016-01-02 12:00", false},
+		{"mon-fri", "", "2016-01-01 00:00", true},
+		{"mon-fri", "", "2016-01-03 12:00", false},
+		// Spanning midnight, belongs to the day it starts
+		{"fri", "22:00-06:00", "2016-01-01 22:00", true},
+		{"fri", "22:00-06:00", "2016-01-01 23:59", true},
+		{"fri", "22:00-06:00", "2016-01-02 00:00", true},
+		{"fri", "22:00-06:00", "2016-01-02 05:59", true},
+		{"fri", "22:00-06:00", "2016-01-02 06:00", false},
+		{"fri", "22:00-06:00", "2016-01-02 22:00", false},
+		{"fri", "22:00-06:00", "2016-01-01 05:00", false},
+		{"fri", "22:00-06:00", "2016-01-01 21:59", false},
+		// Spanning midnight at the end of the week
+		{"sat", "23:00-01:00", "2016-01-03 00:30", true},
+		{"sat", "23:00-01:00", "2016-01-03 23:30", false},
+		{"sun", "23:00-01:00", "2016-01-04 00:30", true},
+	}
+
+	for _, test := range tests {
+		rule, err := parseScheduleRule(scheduleRuleConfig{Days: test.days, Hours: test.hours, Containers: &six})
+		if err != nil {
+			t.Errorf("%s %s: %s", test.days, test.hours, err)
+			continue
+		}
+		at, err := time.Parse("2006-01-02 15:04", test.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := rule.match(at); got != test.match {
+			t.Errorf("%s %s at %s (%s): got %t, want %t", test.days, test.hours, test.time, at.Weekday(), got, test.match)
+		}
+	}
+}
+
+func TestParseScheduleRule(t *testing.T) {
+	zero, negative := 0, -1
+
+	tests := []struct {
+		config scheduleRuleConfig
+		err    bool
+	}{
+		{scheduleRuleConfig{Days: "mon", Hours: "22:00-06:00", Containers: &zero}, false},
+		{scheduleRuleConfig{Days: "mon", Hours: "08:00-08:00", Containers: &zero}, true},
+		{scheduleRuleConfig{Days: "mon", Hours: "08:00", Containers: &zero}, true},
+		{scheduleRuleConfig{Days: "mon", Hours: "8h-20h", Containers: &zero}, true},
+		{scheduleRuleConfig{Days: "mon", Hours: "24:00-06:00", Containers: &zero}, true},
+		{scheduleRuleConfig{Days: "mon"}, true},
+		{scheduleRuleConfig{Days: "mon", Containers: &negative}, true},
+	}
+
+	for _, test := range tests {
+		_, err := parseScheduleRule(test.config)
+		if test.err && err == nil {
+			t.Errorf("%s %s: expected an error", test.config.Days, test.config.Hours)
+		} else if !test.err && err != nil {
+			t.Errorf("%s %s: %s", test.config.Days, test.config.Hours, err)
+		}
+	}
+}
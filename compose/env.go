@@ -0,0 +1,63 @@
+package compose
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/runabove/sail/internal"
+)
+
+// injectEnv adds env, a list of KEY=val, to the environment of every service
+// of a compose file. Variables explicitly set in the compose file take precedence.
+func injectEnv(payload []byte, env []string) ([]byte, error) {
+	var compose map[string]interface{}
+	if err := yaml.Unmarshal(payload, &compose); err != nil {
+		return nil, fmt.Errorf("Invalid compose file: %s", err)
+	}
+
+	// Version 2 files hold services in a 'services' section
+	services := compose
+	if _, ok := compose["version"]; ok {
+		services, _ = compose["services"].(map[string]interface{})
+	}
+
+	for _, raw := range services {
+		service, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		service["environment"] = internal.MergeEnv(env, environmentList(service["environment"]))
+	}
+
+	return yaml.Marshal(compose)
+}
+
+// environmentList converts a compose 'environment' section, either a list or a map, to a list of KEY=val
+func environmentList(raw interface{}) []string {
+	var env []string
+
+	switch e := raw.(type) {
+	case []interface{}:
+		for _, kv := range e {
+			env = append(env, fmt.Sprint(kv))
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(e))
+		for key := range e {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if e[key] == nil {
+				env = append(env, key)
+			} else {
+				env = append(env, fmt.Sprintf("%s=%v", key, e[key]))
+			}
+		}
+	}
+
+	return env
+}
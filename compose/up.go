@@ -8,13 +8,15 @@ import (
 	"strings"
 
 	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	upFile    string
-	upProject string
+	upFile     string
+	upProject  string
+	upEnvFiles internal.EnvFilesValue
 )
 
 func cmdComposeUp() *cobra.Command {
@@ -32,6 +34,7 @@ func cmdComposeUp() *cobra.Command {
 
 	cmd.Flags().StringVarP(&upFile, "file", "", "docker-compose.yml", "Specify an alternate compose file")
 	cmd.Flags().StringVarP(&upProject, "project-name", "p", wd, "Specify an alternate project name (default: directory name)")
+	cmd.Flags().Var(&upEnvFiles, "env-file", "Add variables from a .env file to every service environment")
 
 	return cmd
 }
@@ -44,7 +47,7 @@ func cmdUp(cmd *cobra.Command, args []string) {
 	// Check args
 	if len(args) > 1 {
 		internal.Exit("Invalid usage. sail compose up [<application>]. Please see sail compose up -h\n")
-	} else if len(args) > 1 {
+	} else if len(args) == 1 {
 		ns = args[0]
 	} else {
 		ns = internal.User
@@ -56,6 +59,27 @@ func cmdUp(cmd *cobra.Command, args []string) {
 		internal.Exit("Error reading compose file: %s\n", err)
 	}
 
+	// Inject env files
+	if len(upEnvFiles) > 0 {
+		var envs [][]string
+		for _, path := range upEnvFiles {
+			env, err := internal.ReadEnvFile(path)
+			if err != nil {
+				internal.Exit("Error reading env file: %s\n", err)
+			}
+			envs = append(envs, env)
+		}
+
+		payload, err = injectEnv(payload, internal.MergeEnv(envs...))
+		internal.Check(err)
+	}
+
+	// Keep current definitions for the deployment journal
+	before, err := service.Snapshot(ns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record deployment history: %s\n", err)
+	}
+
 	// Execute request
 	path := fmt.Sprintf("/applications/%s/fig/up", ns)
 	buffer, _, err := internal.Stream("POST", path, payload, internal.SetHeader("Content-Type", "application/x-yaml"))
@@ -72,8 +96,15 @@ func cmdUp(cmd *cobra.Command, args []string) {
 			return
 		}
 
+		var services []string
 		for i := range data {
 			fmt.Printf("Compose operation for service %v is %v\n", data[i]["name"], data[i]["result"])
+			if name, ok := data[i]["name"].(string); ok {
+				services = append(services, name)
+			}
+		}
+		if before != nil {
+			service.RecordApply(ns, before, services)
 		}
 	}
 }
@@ -14,6 +14,7 @@ func init() {
 	Cmd.AddCommand(cmdContainerShow)
 	Cmd.AddCommand(cmdContainerAttach)
 	Cmd.AddCommand(cmdContainerLogs())
+	Cmd.AddCommand(cmdContainerWait())
 }
 
 // Cmd container
@@ -6,15 +6,20 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/google/go-querystring/query"
 	"github.com/spf13/cobra"
 
 	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
 )
 
 var (
-	logsBody Logs
+	logsBody     Logs
+	logsFollow   bool
+	logsSince    time.Duration
+	logsInterval time.Duration
 )
 
 func cmdContainerLogs() *cobra.Command {
@@ -22,14 +27,23 @@ func cmdContainerLogs() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "logs",
 		Short: "Fetch the logs of a container",
-		Long:  "Fetch the logs of a container",
-		Run:   cmdLogs,
+		Long: `Fetch the logs of a container: sail container logs <containerId> [--follow]
+
+With --follow, the logs are polled every --interval and new lines are printed
+until interrupted. Polls resume after transient errors. --since prints the lines
+of the last period first, instead of the --tail last ones. On this command, -f
+is the shorthand of --follow, not of --format.
+`,
+		Run: cmdLogs,
 	}
 
 	cmd.Flags().IntVarP(&logsBody.Tail, "tail", "", 0, "Return N last lines, before offset.")
 	cmd.Flags().IntVarP(&logsBody.Head, "head", "", 0, "Return N first lines, after offset.")
 	cmd.Flags().IntVarP(&logsBody.Offset, "offset", "", 0, "Offset result by N line")
 	cmd.Flags().StringVarP(&logsBody.Period, "period", "", "", "Human readable (Lucene syntax) period")
+	service.BindFollowFlag(cmd, &logsFollow)
+	cmd.Flags().DurationVar(&logsSince, "since", 0, "With --follow, first print the lines of this last period")
+	cmd.Flags().DurationVar(&logsInterval, "interval", 2*time.Second, "With --follow, time between two polls")
 
 	return cmd
 }
@@ -66,9 +80,31 @@ func cmdLogs(cmd *cobra.Command, args []string) {
 
 	// Get args
 	logsBody.Container = container
+
+	if logsFollow {
+		followContainerLogs(logsBody)
+		return
+	}
+	if logsSince > 0 {
+		internal.Exit("Error: --since can only be used with --follow\n")
+	}
 	containerLogs(logsBody)
 }
 
+func followContainerLogs(args Logs) {
+	if args.Head > 0 || args.Offset > 0 {
+		internal.Exit("Error: --head and --offset can not be used with --follow\n")
+	}
+	if logsInterval < time.Second {
+		internal.Exit("Error: --interval must be at least 1s\n")
+	}
+
+	params, err := query.Values(args)
+	internal.Check(err)
+
+	service.FollowLogs(fmt.Sprintf("/containers/%s/logs", args.Container), params, args.Tail, logsSince, logsInterval)
+}
+
 func containerLogs(args Logs) {
 	queryArgs, err := query.Values(args)
 	if err != nil {
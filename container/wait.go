@@ -0,0 +1,91 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
+)
+
+func cmdContainerWait() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait until a container reaches a state: sail container wait [<applicationName>/]<containerId> [--state running|stopped] [--timeout 5m]",
+		Long: `Wait until a container reaches a state: sail container wait [<applicationName>/]<containerId> [--state running|stopped] [--timeout 5m]
+
+Blocks until the container is in the wanted state and prints its state
+transitions. The event stream of its service is followed and the state is
+polled as well, in case the stream drops.
+
+Exit status is 0 when the state is reached, 3 on timeout and 4 when the
+container exits while waiting for 'running' or is deleted.
+	"example: sail container wait my-app/web-1 --state stopped"
+`,
+		Run: cmdWait,
+	}
+
+	service.BindWaitFlags(cmd)
+
+	return cmd
+}
+
+func cmdWait(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail container wait [<applicationName>/]<containerId>. Please see sail container wait --help")
+		os.Exit(1)
+	}
+
+	host, app, container, _, err := internal.ParseResourceName(args[0])
+	internal.Check(err)
+
+	if !internal.CheckHostConsistent(host) {
+		fmt.Fprintf(os.Stderr, "Error: Invalid Host %s for endpoint %s\n", host, internal.Host)
+		os.Exit(1)
+	}
+
+	var status struct {
+		State   string `json:"state"`
+		Service string `json:"service"`
+	}
+	fetchStatus := func() error {
+		b, code, err := internal.Request("GET", fmt.Sprintf("/containers/%s", container), nil)
+		if err != nil {
+			return err
+		} else if e := internal.DecodeError(b); e != nil {
+			return e
+		} else if code >= 400 {
+			return fmt.Errorf("unexpected status code %d", code)
+		}
+		return json.Unmarshal(b, &status)
+	}
+	state, timeout := service.WaitFlags()
+	if err := fetchStatus(); err != nil {
+		service.ExitWait(container, state, timeout, err)
+	}
+
+	tracker := service.NewStateTracker(func(ev *internal.Event) error {
+		service.PrintTransition(ev)
+		return service.UnexpectedTransition(ev, state)
+	})
+
+	err = service.WaitFor(app, status.Service, timeout, func(ev *internal.Event) error {
+		if ev.ID != container {
+			return nil
+		}
+		return tracker.Event(ev)
+	}, func() (bool, error) {
+		if err := fetchStatus(); err != nil {
+			return false, err
+		}
+		if err := tracker.Poll(status.Service, container, status.State); err != nil {
+			return false, err
+		}
+		return strings.EqualFold(status.State, state), nil
+	})
+	service.ExitWait(container, state, timeout, err)
+}
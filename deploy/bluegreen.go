@@ -0,0 +1,198 @@
+package deploy
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
+)
+
+// Flags shared by bluegreen and switch
+var (
+	deploySuffix        string
+	deployTimeout       time.Duration
+	deployHealthURL     string
+	deployHealthTimeout time.Duration
+)
+
+var bluegreenTag string
+
+func bindCommonFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&deploySuffix, "suffix", "-green", "suffix of the shadow service name")
+	cmd.Flags().DurationVar(&deployTimeout, "timeout", 5*time.Minute, "maximum time to wait for all containers to be running")
+	cmd.Flags().StringVar(&deployHealthURL, "health-url", "", "HTTP URL to probe before switching routes, must answer with a 2xx or 3xx status")
+	cmd.Flags().DurationVar(&deployHealthTimeout, "health-timeout", time.Minute, "maximum time to wait for the health URL")
+}
+
+func bluegreenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bluegreen",
+		Short: "Deploy a new tag on a shadow service and switch routes to it: sail deploy bluegreen [<applicationName>/]<serviceId> --tag <tag>",
+		Long: `Deploy a new tag on a shadow service and switch routes to it: sail deploy bluegreen [<applicationName>/]<serviceId> --tag <tag>
+
+The service and its shadow, named <serviceId><suffix> ('web' and 'web-green'),
+take turns serving the HTTP routes. The one without routes is created or
+redeployed with the definition of the live one and the new tag. Once all its
+containers are running and the optional --health-url answers, the routes are
+moved to it and the previous one is stopped. It is kept so that
+'sail deploy switch' can switch back instantly.
+
+Routes are not changed if the new version fails to start. They are attached to
+the new version before being detached from the previous one, so that they are
+always served. If a route can not be attached, the routes are left on the
+previous version.
+	"example: sail deploy bluegreen my-app/web --tag v2 --health-url http://web-green.my-app.sailabove.io/health"
+`,
+		Run: cmdBluegreen,
+	}
+
+	cmd.Flags().StringVar(&bluegreenTag, "tag", "", "tag to deploy")
+	bindCommonFlags(cmd)
+
+	return cmd
+}
+
+func cmdBluegreen(cmd *cobra.Command, args []string) {
+	if len(args) != 1 || bluegreenTag == "" {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail deploy bluegreen [<applicationName>/]<serviceId> --tag <tag>. Please see sail deploy bluegreen --help")
+		os.Exit(1)
+	}
+
+	app, blue := parseService(args[0])
+	live, idle := liveAndIdle(app, blue)
+
+	// Deploy the new version on the idle service
+	spec := service.RewriteSpec(service.FetchServiceSpec(app, live), app, idle)
+	spec.RepositoryTag = bluegreenTag
+
+	if exists(app, idle) {
+		fmt.Fprintf(os.Stderr, "Redeploying %s/%s with %s:%s...\n", app, idle, spec.Repository, spec.RepositoryTag)
+		service.RedeploySpec(spec)
+		if service.State(app, idle) != "running" {
+			service.StartService(app, idle)
+		}
+	} else {
+		service.CreateService(spec)
+	}
+
+	switchTo(app, live, idle)
+}
+
+// parseService splits a service name and exits on error
+func parseService(name string) (string, string) {
+	host, app, svc, _, err := internal.ParseResourceName(name)
+	internal.Check(err)
+
+	if !internal.CheckHostConsistent(host) {
+		fmt.Fprintf(os.Stderr, "Error: Invalid Host %s for endpoint %s\n", host, internal.Host)
+		os.Exit(1)
+	}
+	return app, svc
+}
+
+// exists tells whether a service exists
+func exists(app, name string) bool {
+	for _, svc := range service.ListServices(app) {
+		if svc == name {
+			return true
+		}
+	}
+	return false
+}
+
+// liveAndIdle returns which of blue and its shadow currently serves the routes.
+// blue is considered live when no route is attached.
+func liveAndIdle(app, blue string) (live, idle string) {
+	green := blue + deploySuffix
+
+	if len(service.ListRoutes(app, blue)) == 0 && exists(app, green) && len(service.ListRoutes(app, green)) > 0 {
+		return green, blue
+	}
+	return blue, green
+}
+
+// switchTo waits for idle to be ready, moves the routes of live to it and stops live
+func switchTo(app, live, idle string) {
+	fmt.Fprintf(os.Stderr, "Waiting for %s/%s to be running...\n", app, idle)
+	err := service.WaitState(app, idle, "running", deployTimeout, func(ev *internal.Event) error {
+		service.PrintTransition(ev)
+		return nil
+	})
+	if err == service.ErrWaitTimeout {
+		internal.Exit("Error: %s/%s is not running after %s. Routes are left on %s/%s\n", app, idle, deployTimeout, app, live)
+	}
+	internal.Check(err)
+
+	if deployHealthURL != "" {
+		fmt.Fprintf(os.Stderr, "Probing %s...\n", deployHealthURL)
+		if err := probe(deployHealthURL, deployHealthTimeout); err != nil {
+			internal.Exit("Error: health check failed: %s. Routes are left on %s/%s\n", err, app, live)
+		}
+	}
+
+	routes := service.ListRoutes(app, live)
+	moveRoutes(app, live, idle, routes)
+
+	fmt.Fprintf(os.Stderr, "Stopping %s/%s...\n", app, live)
+	service.StopService(app, live)
+
+	fmt.Fprintf(os.Stderr, "%s/%s is live with %d route(s), %s/%s is stopped\n", app, idle, len(routes), app, live)
+}
+
+// moveRoutes attaches all the routes to idle first, so that they are always
+// served, then detaches them from live. If an attach fails, the routes already
+// attached to idle are detached again and the routes are left on live.
+func moveRoutes(app, live, idle string, routes []service.Route) {
+	for i, r := range routes {
+		err := service.AttachRoute(app, idle, r.Domain, r.Pattern, r.Method)
+		if err == nil {
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		for _, attached := range routes[:i] {
+			if err := service.DetachRoute(app, idle, attached.Domain, attached.Pattern, attached.Method); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+			}
+		}
+		internal.Exit("Error: routes are left on %s/%s\n", app, live)
+	}
+
+	failed := false
+	for _, r := range routes {
+		if err := service.DetachRoute(app, live, r.Domain, r.Pattern, r.Method); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			failed = true
+		}
+	}
+	if failed {
+		internal.Exit("Error: some routes are still attached to %s/%s too, it is left running\n", app, live)
+	}
+}
+
+// probe requests url until it answers with a 2xx or 3xx status or timeout expires
+func probe(url string, timeout time.Duration) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 400 {
+				return nil
+			}
+			err = fmt.Errorf("%s answered %s", url, resp.Status)
+		}
+
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
@@ -0,0 +1,15 @@
+package deploy
+
+import "github.com/spf13/cobra"
+
+func init() {
+	Cmd.AddCommand(bluegreenCmd())
+	Cmd.AddCommand(switchCmd())
+}
+
+// Cmd deploy
+var Cmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deployment strategies: sail deploy --help",
+	Long:  `Deployment strategies: sail deploy <command>`,
+}
@@ -0,0 +1,51 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/service"
+)
+
+func switchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "switch",
+		Short: "Switch routes between a service and its shadow: sail deploy switch [<applicationName>/]<serviceId>",
+		Long: `Switch routes between a service and its shadow: sail deploy switch [<applicationName>/]<serviceId>
+
+Starts the stopped one of <serviceId> and <serviceId><suffix>, waits for all its
+containers to be running and the optional --health-url, then moves the routes
+to it and stops the other one. See 'sail deploy bluegreen'.
+	"example: sail deploy switch my-app/web"
+`,
+		Run: cmdSwitch,
+	}
+
+	bindCommonFlags(cmd)
+
+	return cmd
+}
+
+func cmdSwitch(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail deploy switch [<applicationName>/]<serviceId>. Please see sail deploy switch --help")
+		os.Exit(1)
+	}
+
+	app, blue := parseService(args[0])
+	live, idle := liveAndIdle(app, blue)
+
+	if !exists(app, idle) {
+		internal.Exit("Error: %s/%s does not exist. Please see sail deploy bluegreen --help\n", app, idle)
+	}
+
+	if service.State(app, idle) != "running" {
+		fmt.Fprintf(os.Stderr, "Starting %s/%s...\n", app, idle)
+		service.StartService(app, idle)
+	}
+
+	switchTo(app, live, idle)
+}
@@ -0,0 +1,217 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadEnvFile reads a Docker .env file and returns its variables as a list of KEY=val
+func ReadEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	env, err := ParseEnvFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return env, nil
+}
+
+// ParseEnvFile parses .env syntax and returns variables as a list of KEY=val, in file order.
+//
+// Blank lines and lines starting with '#' are ignored, as well as an optional
+// 'export ' prefix. Values may be single quoted (literal), or double quoted
+// (supports \n, \t, \" and \\ escapes). Quoted values may span multiple lines.
+// Unquoted values are trimmed and end at a ' #' comment. A line holding only
+// a KEY takes its value from the local environment, if set.
+func ParseEnvFile(r io.Reader) ([]string, error) {
+	var env []string
+
+	reader := bufio.NewReader(r)
+	lineno := 0
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		lineno++
+		start := lineno
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "export "))
+
+			kv := strings.SplitN(trimmed, "=", 2)
+			key := strings.TrimSpace(kv[0])
+			if key == "" || strings.ContainsAny(key, " \t\"'") {
+				return nil, fmt.Errorf("line %d: invalid variable name '%s'", start, key)
+			}
+
+			if len(kv) == 1 {
+				// Take value from local environment, if any
+				if val, ok := os.LookupEnv(key); ok {
+					env = append(env, key+"="+val)
+				}
+			} else {
+				raw := strings.TrimLeft(kv[1], " \t")
+
+				// Quoted values may span multiple lines: read until closing quote
+				if strings.HasPrefix(raw, "\"") || strings.HasPrefix(raw, "'") {
+					for !closedQuote(raw) && err != io.EOF {
+						var next string
+						next, err = reader.ReadString('\n')
+						if err != nil && err != io.EOF {
+							return nil, err
+						}
+						lineno++
+						raw += "\n" + strings.TrimRight(next, "\r\n")
+					}
+				}
+
+				val, perr := parseEnvValue(raw)
+				if perr != nil {
+					return nil, fmt.Errorf("line %d: %s", start, perr)
+				}
+				env = append(env, key+"="+val)
+			}
+		}
+
+		if err == io.EOF {
+			return env, nil
+		}
+	}
+}
+
+// closedQuote tells whether a quoted value has its closing quote
+func closedQuote(raw string) bool {
+	quote := raw[0]
+	for i := 1; i < len(raw); i++ {
+		if quote == '"' && raw[i] == '\\' {
+			i++
+			continue
+		}
+		if raw[i] == quote {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEnvValue decodes the right hand side of a KEY=val line
+func parseEnvValue(raw string) (string, error) {
+	raw = strings.TrimRight(raw, "\r\n")
+
+	if raw == "" {
+		return "", nil
+	}
+
+	switch raw[0] {
+	case '\'':
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single quoted value")
+		}
+		return raw[1 : end+1], checkTrailing(raw[end+2:])
+	case '"':
+		var out []byte
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			if c == '\\' && i+1 < len(raw) {
+				i++
+				switch raw[i] {
+				case 'n':
+					out = append(out, '\n')
+				case 't':
+					out = append(out, '\t')
+				case 'r':
+					out = append(out, '\r')
+				default:
+					out = append(out, raw[i])
+				}
+				continue
+			}
+			if c == '"' {
+				return string(out), checkTrailing(raw[i+1:])
+			}
+			out = append(out, c)
+		}
+		return "", fmt.Errorf("unterminated double quoted value")
+	}
+
+	// Unquoted: strip inline comment
+	if i := strings.Index(raw, " #"); i >= 0 {
+		raw = raw[:i]
+	}
+	return strings.TrimSpace(raw), nil
+}
+
+// checkTrailing only allows blanks or a comment after a quoted value
+func checkTrailing(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected characters after quoted value: '%s'", rest)
+	}
+	return nil
+}
+
+// MergeEnv merges lists of KEY=val. Later values override earlier ones while
+// keys keep the position of their first occurrence.
+func MergeEnv(lists ...[]string) []string {
+	var merged []string
+	index := make(map[string]int)
+
+	for _, list := range lists {
+		for _, kv := range list {
+			key := strings.SplitN(kv, "=", 2)[0]
+			if i, ok := index[key]; ok {
+				merged[i] = kv
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, kv)
+		}
+	}
+
+	return merged
+}
+
+// FormatEnvLine formats a variable as a .env line that ParseEnvFile reads back identically
+func FormatEnvLine(key, value string) string {
+	if value == "" || strings.IndexFunc(value, needsQuote) < 0 {
+		return key + "=" + value
+	}
+
+	r := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\r", "\\r", "\t", "\\t")
+	return key + "=\"" + r.Replace(value) + "\""
+}
+
+func needsQuote(c rune) bool {
+	return strings.ContainsRune(" \t\r\n#'\"\\", c)
+}
+
+// EnvFilesValue is a flag value collecting .env file paths, one per flag
+// occurrence. Paths are never split on commas.
+type EnvFilesValue []string
+
+// Set appends a path
+func (v *EnvFilesValue) Set(path string) error {
+	*v = append(*v, path)
+	return nil
+}
+
+// String returns the paths as they are printed in usage
+func (v *EnvFilesValue) String() string {
+	return "[" + strings.Join(*v, ",") + "]"
+}
+
+// Type returns the type name of the value
+func (v *EnvFilesValue) Type() string {
+	return "envFiles"
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// ImpactUnknown returns the impact to display when it could not be computed, so
+// that a confirmation is still asked
+func ImpactUnknown(err error) []string {
+	return []string{fmt.Sprintf("could not compute impact: %s", err)}
+}
+
+// ServicesImpact describes which services of an application an action affects.
+// describe is called with the definition of each service, in name order, and
+// returns what happens to it, or an empty string if it is not affected. The
+// services are not fetched all at once by the API, a failure ends the list.
+func ServicesImpact(app string, describe func(name string, definition []byte) (string, error)) []string {
+	var impact []string
+	var services []string
+
+	if err := GetJSON(fmt.Sprintf("/applications/%s/services", app), &services); err != nil {
+		return ImpactUnknown(err)
+	}
+	sort.Strings(services)
+
+	for _, name := range services {
+		var definition json.RawMessage
+		if err := GetJSON(fmt.Sprintf("/applications/%s/services/%s", app, name), &definition); err != nil {
+			return append(impact, ImpactUnknown(err)...)
+		}
+
+		line, err := describe(name, definition)
+		if err != nil {
+			return append(impact, ImpactUnknown(err)...)
+		}
+		if line != "" {
+			impact = append(impact, line)
+		}
+	}
+	return impact
+}
+
+// GetJSON GETs path into v and returns an error instead of exiting when it fails
+func GetJSON(path string, v interface{}) error {
+	b, code, err := Request("GET", path, nil)
+	if err != nil {
+		return err
+	} else if code != http.StatusOK {
+		if e := DecodeError(b); e != nil {
+			return e
+		}
+		return fmt.Errorf("unexpected status code %d", code)
+	}
+	return json.Unmarshal(b, v)
+}
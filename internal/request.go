@@ -212,6 +212,16 @@ func doRequest(method string, path string, args []byte, mods ...RequestModifier)
 
 // DisplayStream decode each line from http buffer and print either message or error. Return last read line
 func DisplayStream(buffer io.ReadCloser) ([]byte, error) {
+	return ReadStream(buffer, func(message string) {
+		fmt.Fprintln(os.Stderr, message)
+	}, func(line []byte) {
+		fmt.Printf(string(line))
+	})
+}
+
+// ReadStream decode each line from http buffer and pass progress messages to progress and
+// other lines, but the last one, to output. Return last read line
+func ReadStream(buffer io.ReadCloser, progress func(string), output func([]byte)) ([]byte, error) {
 	reader := bufio.NewReader(buffer)
 
 	for {
@@ -229,7 +239,7 @@ func DisplayStream(buffer io.ReadCloser) ([]byte, error) {
 		// Progress message
 		m := DecodeMessage(line)
 		if m != nil {
-			fmt.Fprintln(os.Stderr, m.Message)
+			progress(m.Message)
 			continue
 		}
 
@@ -245,7 +255,7 @@ func DisplayStream(buffer io.ReadCloser) ([]byte, error) {
 		}
 
 		// Default
-		fmt.Printf(string(line))
+		output(line)
 	}
 }
 
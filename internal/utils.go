@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -8,6 +9,8 @@ import (
 	"os/signal"
 	"strings"
 	"sync"
+
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 // Exit func display an error message on stderr and exit 1
@@ -16,6 +19,37 @@ func Exit(format string, args ...interface{}) {
 	os.Exit(1)
 }
 
+// Confirm asks a yes/no question on stderr and reads the answer from stdin. Default is no.
+func Confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// IsTerminal tells whether stdin is a terminal, that is whether questions can be asked
+func IsTerminal() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// ConfirmImpact asks to confirm a destructive action when stdin is a terminal,
+// after listing what impact returns it will affect. It returns true without
+// asking, nor calling impact, when yes is set or stdin is not a terminal.
+func ConfirmImpact(yes bool, question string, impact func() []string) bool {
+	if yes || !IsTerminal() {
+		return true
+	}
+
+	if lines := impact(); len(lines) > 0 {
+		fmt.Fprintln(os.Stderr, "This will affect:")
+		for _, line := range lines {
+			fmt.Fprintf(os.Stderr, "  - %s\n", line)
+		}
+	}
+	return Confirm(question)
+}
+
 // Message type
 type Message struct {
 	Message string `json:"message"`
@@ -152,3 +186,22 @@ func ExitAfterCtrlC() {
 
 	endWaiter.Wait()
 }
+
+// StringIn tells whether list holds s
+func StringIn(s string, list []string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ToLower returns the items of list in lower case
+func ToLower(list []string) []string {
+	lower := make([]string, len(list))
+	for i, item := range list {
+		lower[i] = strings.ToLower(item)
+	}
+	return lower
+}
@@ -10,6 +10,7 @@ func init() {
 	cmdMeSSHKey.AddCommand(cmdMeSSHKeyList)
 	cmdMeSSHKey.AddCommand(cmdMeSSHKeyAdd)
 	cmdMeSSHKey.AddCommand(cmdMeSSHKeyDelete)
+	cmdMeSSHKeyDelete.Flags().BoolVar(&sshKeyDeleteYes, "yes", false, "do not ask for confirmation")
 
 	Cmd.AddCommand(cmdMeSSHKey)
 }
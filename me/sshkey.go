@@ -65,12 +65,17 @@ examples:
 	},
 }
 
+var sshKeyDeleteYes bool
+
 var cmdMeSSHKeyDelete = &cobra.Command{
 
 	Use:     "delete",
 	Aliases: []string{"del", "rm"},
 	Short:   "Delete an ssh keys from this account: sail me sshkey delete <fingerprint>",
 	Long: `Delete an ssh keys from this account: sail me sshkey delete <fingerprint>
+
+When stdin is a terminal, the key is shown and confirmation is asked, unless
+--yes is set.
 example :
   sail me sshkey delete 0d/keDZZb3OAjj+8JI7T5iIxMLUT643YfW3mBznqrC8=`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -131,6 +136,11 @@ func sshKeyAdd(keyLine, keyName string) {
 }
 
 func sshKeyDelete(fingerprint string) {
+	if !internal.ConfirmImpact(sshKeyDeleteYes, "Delete this ssh key?", func() []string { return sshKeyImpact(fingerprint) }) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(1)
+	}
+
 	urlEscape := url.QueryEscape(fingerprint)
 
 	path := "/user/keys"
@@ -145,3 +155,19 @@ func sshKeyDelete(fingerprint string) {
 	BaseURL.RawQuery = params.Encode()
 	internal.FormatOutputDef(internal.DeleteWantJSON(BaseURL.String()))
 }
+
+// sshKeyImpact describes the key to delete
+func sshKeyImpact(fingerprint string) []string {
+	var keys []struct {
+		Name        string `json:"name"`
+		Fingerprint string `json:"fingerprint"`
+	}
+	internal.Check(json.Unmarshal(internal.ReqWant("GET", http.StatusOK, "/user/keys", nil), &keys))
+
+	for _, key := range keys {
+		if key.Fingerprint == fingerprint {
+			return []string{fmt.Sprintf("ssh key '%s' (%s) will be removed from your account", key.Name, key.Fingerprint)}
+		}
+	}
+	return []string{fmt.Sprintf("no ssh key of your account has fingerprint %s", fingerprint)}
+}
@@ -0,0 +1,62 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/runabove/sail/internal"
+)
+
+// List returns the names of the private networks of an application
+func List(app string) []string {
+	var networks []string
+	b := internal.ReqWant("GET", http.StatusOK, fmt.Sprintf("/applications/%s/networks", app), nil)
+	internal.Check(json.Unmarshal(b, &networks))
+	return networks
+}
+
+// Get returns the subnet and the allocation ranges of a private network
+func Get(app, name string) (string, []string) {
+	var network map[string]interface{}
+	var ranges []string
+
+	b := internal.ReqWant("GET", http.StatusOK, fmt.Sprintf("/applications/%s/networks/%s", app, name), nil)
+	internal.Check(json.Unmarshal(b, &network))
+
+	brange := internal.ReqWant("GET", http.StatusOK, fmt.Sprintf("/applications/%s/networks/%s/ranges", app, name), nil)
+	internal.Check(json.Unmarshal(brange, &ranges))
+
+	subnet, _ := network["subnet"].(string)
+	return subnet, ranges
+}
+
+// Create creates a private network with its allocation ranges
+func Create(app, name, subnet string, ranges []string) {
+	body, err := json.Marshal(networkAddStruct{Subnet: subnet})
+	internal.Check(err)
+
+	internal.PostBodyWantJSON(fmt.Sprintf("/applications/%s/networks/%s", app, name), body)
+	AddRanges(app, name, ranges)
+}
+
+// AddRanges adds allocation ranges to a private network
+func AddRanges(app, name string, ranges []string) {
+	for _, r := range ranges {
+		internal.PostWantJSON(fmt.Sprintf("/applications/%s/networks/%s/ranges/%s", app, name, r))
+	}
+}
+
+// Delete deletes a private network
+func Delete(app, name string) {
+	internal.DeleteWantJSON(fmt.Sprintf("/applications/%s/networks/%s", app, name))
+}
+
+// Copy creates private network srcApp/srcName as dstApp/dstName, with the same subnet and allocation ranges
+func Copy(srcApp, srcName, dstApp, dstName string) {
+	subnet, ranges := Get(srcApp, srcName)
+	Create(dstApp, dstName, subnet, ranges)
+
+	fmt.Fprintf(os.Stderr, "Copied network %s/%s to %s/%s\n", srcApp, srcName, dstApp, dstName)
+}
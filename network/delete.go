@@ -1,6 +1,7 @@
 package network
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
@@ -11,11 +12,16 @@ import (
 
 const cmdNetDelUsage = "Remove a private network: sail network delete [<applicationName>/]<networkId>"
 
+var networkDeleteYes bool
+
 var cmdNetworkDelete = &cobra.Command{
 	Use:     "delete",
 	Aliases: []string{"del", "rm", "remove"},
 	Short:   cmdNetDelUsage,
-	Long:    cmdNetDelUsage,
+	Long: cmdNetDelUsage + `
+
+When stdin is a terminal, the services connected to the network are listed and
+confirmation is asked, unless --yes is set.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
 			fmt.Fprintln(os.Stderr, "Invalid usage. sail network delete <applicationName>/<networkId>. Please see sail network delete --help")
@@ -38,6 +44,12 @@ func networkRemove(networkID string) {
 		os.Exit(1)
 	}
 
+	question := fmt.Sprintf("Delete network %s/%s?", app, net)
+	if !internal.ConfirmImpact(networkDeleteYes, question, func() []string { return networkImpact(app, net) }) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(1)
+	}
+
 	path := fmt.Sprintf("/applications/%s/networks/%s", app, net)
 	data := internal.DeleteWantJSON(path)
 
@@ -45,3 +57,20 @@ func networkRemove(networkID string) {
 		fmt.Fprintf(os.Stderr, "Deleted network %s/%s\n", app, net)
 	})
 }
+
+// networkImpact lists the services connected to a network
+func networkImpact(app, net string) []string {
+	return internal.ServicesImpact(app, func(name string, definition []byte) (string, error) {
+		var service struct {
+			ContainerNetwork map[string]interface{} `json:"container_network"`
+		}
+		if err := json.Unmarshal(definition, &service); err != nil {
+			return "", err
+		}
+
+		if _, ok := service.ContainerNetwork[net]; ok {
+			return fmt.Sprintf("service %s/%s is connected to it", app, name), nil
+		}
+		return "", nil
+	})
+}
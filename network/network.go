@@ -8,6 +8,8 @@ func init() {
 	Cmd.AddCommand(cmdNetworkList)
 	Cmd.AddCommand(cmdNetworkRangeAdd)
 	Cmd.AddCommand(cmdNetworkDelete)
+
+	cmdNetworkDelete.Flags().BoolVar(&networkDeleteYes, "yes", false, "do not ask for confirmation")
 }
 
 // Cmd network
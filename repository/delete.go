@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
@@ -9,11 +10,16 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var repositoryDeleteYes bool
+
 var cmdRepositoryDelete = &cobra.Command{
 	Use:     "delete",
 	Aliases: []string{"del", "rm", "remove"},
 	Short:   "Delete a repository: sail repository delete <applicationName>/<repositoryId>",
-	Long:    `Delete a repository: sail repository delete <applicationName>/<repositoryId>`,
+	Long: `Delete a repository: sail repository delete <applicationName>/<repositoryId>
+
+When stdin is a terminal, the services of the application using the repository
+are listed and confirmation is asked, unless --yes is set.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
 			fmt.Fprintln(os.Stderr, "Invalid usage. sail repository delete <applicationName>/<repositoryId>. Please see sail repository delete --help")
@@ -36,6 +42,12 @@ func repositoryRemove(repositoryID string) {
 		os.Exit(1)
 	}
 
+	question := fmt.Sprintf("Delete repository %s/%s?", app, repo)
+	if !internal.ConfirmImpact(repositoryDeleteYes, question, func() []string { return repositoryImpact(app, repo) }) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(1)
+	}
+
 	path := fmt.Sprintf("/repositories/%s/%s", app, repo)
 	data := internal.DeleteWantJSON(path)
 
@@ -43,3 +55,21 @@ func repositoryRemove(repositoryID string) {
 		fmt.Fprintf(os.Stderr, "Deleted repository %s/%s\n", app, repo)
 	})
 }
+
+// repositoryImpact lists the services of an application using a repository
+func repositoryImpact(app, repo string) []string {
+	return internal.ServicesImpact(app, func(name string, definition []byte) (string, error) {
+		var service struct {
+			Repository    string `json:"repository"`
+			RepositoryTag string `json:"repository_tag"`
+		}
+		if err := json.Unmarshal(definition, &service); err != nil {
+			return "", err
+		}
+
+		if service.Repository == repo || service.Repository == app+"/"+repo {
+			return fmt.Sprintf("service %s/%s runs %s:%s and can not be redeployed anymore", app, name, repo, service.RepositoryTag), nil
+		}
+		return "", nil
+	})
+}
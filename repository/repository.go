@@ -6,6 +6,8 @@ func init() {
 	Cmd.AddCommand(cmdRepositoryAdd)
 	Cmd.AddCommand(cmdRepositoryDelete)
 	Cmd.AddCommand(cmdRepositoryList)
+
+	cmdRepositoryDelete.Flags().BoolVar(&repositoryDeleteYes, "yes", false, "do not ask for confirmation")
 }
 
 // Cmd repository
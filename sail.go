@@ -7,8 +7,10 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/runabove/sail/application"
+	"github.com/runabove/sail/autoscale"
 	"github.com/runabove/sail/compose"
 	"github.com/runabove/sail/container"
+	"github.com/runabove/sail/deploy"
 	"github.com/runabove/sail/internal"
 	"github.com/runabove/sail/me"
 	"github.com/runabove/sail/metric"
@@ -41,15 +43,19 @@ func main() {
 // AddCommands adds child commands to the root command rootCmd.
 func addCommands() {
 	rootCmd.AddCommand(application.Cmd)
+	rootCmd.AddCommand(autoscale.Cmd)
 	rootCmd.AddCommand(compose.Cmd)
 	rootCmd.AddCommand(internal.Cmd)
 	rootCmd.AddCommand(container.Cmd)
+	rootCmd.AddCommand(deploy.Cmd)
 	rootCmd.AddCommand(me.Cmd)
 	rootCmd.AddCommand(metric.Cmd)
 	rootCmd.AddCommand(network.Cmd)
 	rootCmd.AddCommand(repository.Cmd)
 	rootCmd.AddCommand(operation.Cmd)
 	rootCmd.AddCommand(service.Cmd)
+	rootCmd.AddCommand(service.RunCmd)
+	rootCmd.AddCommand(service.TopCmd)
 	rootCmd.AddCommand(update.Cmd)
 	rootCmd.AddCommand(version.Cmd)
 	rootCmd.AddCommand(autocompleteCmd)
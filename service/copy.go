@@ -0,0 +1,157 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+	"github.com/runabove/sail/network"
+)
+
+var copyWithDomains bool
+
+func copyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "copy",
+		Aliases: []string{"cp"},
+		Short:   "Copy a service: sail service copy [<applicationName>/]<serviceId> [<applicationName>/]<serviceId> [--with-domains]",
+		Long: `Copy a service: sail service copy [<applicationName>/]<serviceId> [<applicationName>/]<serviceId> [--with-domains]
+
+The service definition is read and created again in the target application.
+References to the source application in links and networks are rewritten to
+the target application and missing private networks are created with the
+same subnet and ranges. Attached domains are only copied with --with-domains.
+	"example: sail service copy app1/web app2/web"
+`,
+		Run: cmdCopy,
+	}
+
+	cmd.Flags().BoolVar(&copyWithDomains, "with-domains", false, "also attach the domains of the source service")
+
+	return cmd
+}
+
+func cmdCopy(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail service copy [<applicationName>/]<serviceId> [<applicationName>/]<serviceId>. Please see sail service copy --help")
+		os.Exit(1)
+	}
+
+	var apps, services [2]string
+	for i := range args {
+		host, app, service, _, err := internal.ParseResourceName(args[i])
+		internal.Check(err)
+
+		if !internal.CheckHostConsistent(host) {
+			fmt.Fprintf(os.Stderr, "Error: Invalid Host %s for endpoint %s\n", host, internal.Host)
+			os.Exit(1)
+		}
+		apps[i], services[i] = app, service
+	}
+
+	src := FetchServiceSpec(apps[0], services[0])
+	CopyNetworks(src, apps[1])
+	CreateService(RewriteSpec(src, apps[1], services[1]))
+
+	if copyWithDomains {
+		CopyRoutes(apps[0], services[0], apps[1], services[1])
+	}
+}
+
+// RewriteSpec returns a copy of spec for service name of app. References to
+// the source application in links and networks are rewritten to app.
+func RewriteSpec(spec ServiceSpec, app, name string) ServiceSpec {
+	from := spec.Application
+	spec.Application = app
+	spec.Service = name
+
+	if spec.Links != nil {
+		links := make(map[string]string)
+		for target, alias := range spec.Links {
+			links[rewriteRef(target, from, app)] = alias
+		}
+		spec.Links = links
+	}
+
+	if spec.ContainerNetwork != nil {
+		networks := make(map[string]map[string][]string)
+		for net, options := range spec.ContainerNetwork {
+			rewritten := make(map[string][]string)
+			for option, refs := range options {
+				for _, ref := range refs {
+					rewritten[option] = append(rewritten[option], rewriteRef(ref, from, app))
+				}
+			}
+			networks[rewriteRef(net, from, app)] = rewritten
+		}
+		spec.ContainerNetwork = networks
+	}
+
+	if spec.ContainerPorts != nil {
+		ports := make(map[string][]PortConfig)
+		for port, configs := range spec.ContainerPorts {
+			for _, pc := range configs {
+				pc.Network = rewriteRef(pc.Network, from, app)
+				ports[port] = append(ports[port], pc)
+			}
+		}
+		spec.ContainerPorts = ports
+	}
+
+	return spec
+}
+
+// rewriteRef rewrites a reference to application from, or to one of its resources, to application to
+func rewriteRef(ref, from, to string) string {
+	if ref == from {
+		return to
+	}
+	if strings.HasPrefix(ref, from+"/") {
+		return to + strings.TrimPrefix(ref, from)
+	}
+	return ref
+}
+
+// CopyNetworks creates the private networks used by spec which are missing in app
+func CopyNetworks(spec ServiceSpec, app string) {
+	if spec.Application == app {
+		return
+	}
+
+	private := make(map[string]bool)
+	for _, name := range network.List(spec.Application) {
+		private[name] = true
+	}
+	existing := make(map[string]bool)
+	for _, name := range network.List(app) {
+		existing[name] = true
+	}
+
+	for name := range spec.ContainerNetwork {
+		target := rewriteRef(name, spec.Application, app)
+		if private[name] && !existing[target] {
+			network.Copy(spec.Application, name, app, target)
+			existing[target] = true
+		}
+	}
+}
+
+// CreateService adds and starts a service, without attaching its console. Exits on failure.
+func CreateService(spec ServiceSpec) {
+	fmt.Fprintf(os.Stderr, "Creating service %s/%s...\n", spec.Application, spec.Service)
+
+	if created, _ := doServiceAdd(spec, false); !created {
+		os.Exit(1)
+	}
+	doServiceStart(spec.Application, spec.Service)
+}
+
+// CopyRoutes attaches the routes of a service to another one
+func CopyRoutes(srcApp, srcService, dstApp, dstService string) {
+	for _, r := range ListRoutes(srcApp, srcService) {
+		internal.Check(AttachRoute(dstApp, dstService, r.Domain, r.Pattern, r.Method))
+	}
+}
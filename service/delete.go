@@ -1,8 +1,11 @@
 package service
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -15,23 +18,33 @@ func deleteCmd() *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:     "delete",
-		Short:   "Delete a docker service: sail service delete [<applicationName>/]<serviceId> [--force]",
+		Short:   "Delete a docker service: sail service delete [<applicationName>/]<serviceId>... [--force]",
+		Long:    "Delete a docker service: sail service delete [<applicationName>/]<serviceId>... [--force]\n\nWhen stdin is a terminal, the services linking to it, its containers and its routes\nare listed and confirmation is asked, unless --yes is set.\n\n" + selectorUsage,
 		Run:     cmdServiceDelete,
 		Aliases: []string{"del", "rm", "remove"},
 	}
 
 	cmd.Flags().BoolVarP(&deleteForce, "force", "", false, "danger zone: delete service even if it breaks links")
+	bulk.bind(cmd)
 
 	return cmd
 }
 
 func cmdServiceDelete(cmd *cobra.Command, args []string) {
-	usage := "Invalid usage. sail service delete [<applicationName>/]<serviceId>"
-	if len(args) != 1 {
+	usage := "Invalid usage. sail service delete [<applicationName>/]<serviceId>..."
+	if len(args) < 1 {
 		fmt.Fprintln(os.Stderr, usage)
 		return
 	}
 
+	if bulk.active(args) {
+		bulk.impact = deleteImpact
+		bulk.run(args, "Delete", func(s selected, report func(string)) (string, error) {
+			return "", deleteService(s.app, s.service, report)
+		})
+		return
+	}
+
 	// Split namespace and service
 	host, app, service, _, err := internal.ParseResourceName(args[0])
 	internal.Check(err)
@@ -41,18 +54,69 @@ func cmdServiceDelete(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	question := fmt.Sprintf("Delete service %s/%s?", app, service)
+	if !internal.ConfirmImpact(bulk.yes, question, func() []string { return deleteImpact(selected{app, service}) }) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(1)
+	}
+
 	serviceDelete(app, service)
 }
 
-func serviceDelete(namespace string, name string) {
-	path := fmt.Sprintf("/applications/%s/services/%s?force=%t", namespace, name, deleteForce)
-	buffer, _, err := internal.Stream("DELETE", path, nil)
+// deleteImpact describes what deleting a service affects: the services linking
+// to it, its containers and its routes
+func deleteImpact(s selected) []string {
+	impact := internal.ServicesImpact(s.app, func(name string, definition []byte) (string, error) {
+		if name == s.service {
+			return "", nil
+		}
+
+		var spec ServiceSpec
+		if err := json.Unmarshal(definition, &spec); err != nil {
+			return "", err
+		}
 
+		if _, ok := spec.Links[s.service]; !ok {
+			return "", nil
+		}
+		line := fmt.Sprintf("service %s/%s links to it", s.app, name)
+		if deleteForce {
+			line += " and will lose this link"
+		}
+		return line, nil
+	})
+
+	status, err := fetchServiceStatus(s.app, s.service)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(1)
+		return append(impact, internal.ImpactUnknown(err)...)
+	}
+	if len(status.Containers) > 0 {
+		containers := make([]string, 0, len(status.Containers))
+		for name := range status.Containers {
+			containers = append(containers, name)
+		}
+		sort.Strings(containers)
+		impact = append(impact, fmt.Sprintf("%d container(s) will be destroyed: %s", len(containers), strings.Join(containers, ", ")))
 	}
 
-	_, err = internal.DisplayStream(buffer)
-	internal.Check(err)
+	routes, err := listRoutes(s.app, s.service)
+	if err != nil {
+		return append(impact, internal.ImpactUnknown(err)...)
+	}
+	for _, route := range routes {
+		impact = append(impact, fmt.Sprintf("route %s %s%s will lose routing", route.Method, route.Domain, route.Pattern))
+	}
+
+	return impact
+}
+
+func serviceDelete(namespace string, name string) {
+	internal.Check(deleteService(namespace, name, printProgress))
+}
+
+// deleteService issues the delete request and passes its progress to report
+func deleteService(namespace string, name string, report func(string)) error {
+	path := fmt.Sprintf("/applications/%s/services/%s?force=%t", namespace, name, deleteForce)
+	_, err := streamRequest("DELETE", path, nil, report)
+	return err
 }
@@ -10,11 +10,16 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var domainDetachYes bool
+
 var usageDomainDetach = "Invalid usage. sail service domain detach [<applicationName>/]<serviceId> <domain> <pattern> <method>. Please see sail service domain detach --help"
 var cmdDomainDetach = &cobra.Command{
 	Use:     "detach",
 	Aliases: []string{"delete", "del", "rm", "remove"},
 	Short:   "Detach a domain on the HTTP load balancer: sail service domain detach [<applicationName>/]<serviceId> <domain> <pattern> <method>",
+	Long: `Detach a domain on the HTTP load balancer: sail service domain detach [<applicationName>/]<serviceId> <domain> <pattern> <method>
+
+When stdin is a terminal, confirmation is asked, unless --yes is set.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 4 {
 			fmt.Fprintln(os.Stderr, usageDomainDetach)
@@ -44,6 +49,14 @@ func serviceDomainDetach(serviceID, domain string, args domainStruct) {
 	err = internal.CheckName(domain)
 	internal.Check(err)
 
+	question := fmt.Sprintf("Detach route %s %s%s from service %s/%s?", args.Method, domain, args.Pattern, app, service)
+	if !internal.ConfirmImpact(domainDetachYes, question, func() []string {
+		return []string{fmt.Sprintf("route %s %s%s will lose routing", args.Method, domain, args.Pattern)}
+	}) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(1)
+	}
+
 	path := fmt.Sprintf("/applications/%s/services/%s/attached-routes/%s", app, service, domain)
 	data := internal.DeleteBodyWantJSON(path, body)
 
@@ -6,6 +6,8 @@ func init() {
 	Cmd.AddCommand(cmdDomainAttach)
 	Cmd.AddCommand(cmdDomainDetach)
 	Cmd.AddCommand(cmdDomainList)
+
+	cmdDomainDetach.Flags().BoolVar(&domainDetachYes, "yes", false, "do not ask for confirmation")
 }
 
 // Cmd domain
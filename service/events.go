@@ -1,18 +1,47 @@
 package service
 
 import (
+	"bufio"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/runabove/sail/internal"
 	"github.com/spf13/cobra"
 )
 
+var (
+	eventsExec    string
+	eventsPost    string
+	eventsTypes   []string
+	eventsStates  []string
+	eventsRetry   int
+	eventsRate    int
+	eventsTimeout time.Duration
+)
+
 var cmdServiceEvents = &cobra.Command{
 	Use:   "events",
-	Short: "Stream all service events: sail service events <applicationName>/<serviceId>",
-	Long: `Stream all service events: sail service events <applicationName>/<serviceId>
+	Short: "Stream all service events: sail service events <applicationName>/<serviceId> [--exec <command>] [--post <url>]",
+	Long: `Stream all service events: sail service events <applicationName>/<serviceId> [--exec <command>] [--post <url>]
+
+With --exec, the command is run for each event, which it reads as JSON on stdin.
+It is also described by the SAIL_EVENT, SAIL_STATE, SAIL_PREV_STATE,
+SAIL_APPLICATION, SAIL_SERVICE, SAIL_CONTAINER and SAIL_MESSAGE environment
+variables, and by SAIL_EXIT_STATUS or SAIL_SIGNAL when a container exited.
+With --post, each event is posted as JSON to the URL, which must answer with a
+2xx status.
+
+Hooks may be restricted to events of some --type or to some --state. Failed
+hooks are retried --retry times, and at most --rate events per minute trigger
+hooks. A command still running after --timeout is killed and counts as failed.
+The event stream is reopened when it closes or fails.
 	"example: sail service events my-app/myServiceId"
+	"example: sail service events my-app/myServiceId --state stopped --exec ./on-event.sh"
+	"example: sail service events my-app/myServiceId --post https://chat.example/hook"
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
@@ -23,6 +52,16 @@ var cmdServiceEvents = &cobra.Command{
 	},
 }
 
+func init() {
+	cmdServiceEvents.Flags().StringVar(&eventsExec, "exec", "", "run `command` for each event")
+	cmdServiceEvents.Flags().StringVar(&eventsPost, "post", "", "post each event to `url`")
+	cmdServiceEvents.Flags().StringSliceVar(&eventsTypes, "type", nil, "only run hooks for events of these types")
+	cmdServiceEvents.Flags().StringSliceVar(&eventsStates, "state", nil, "only run hooks for events to these states")
+	cmdServiceEvents.Flags().IntVar(&eventsRetry, "retry", 3, "number of retries of a failed hook")
+	cmdServiceEvents.Flags().IntVar(&eventsRate, "rate", 30, "maximum number of events per minute triggering hooks, 0 for no limit")
+	cmdServiceEvents.Flags().DurationVar(&eventsTimeout, "timeout", time.Minute, "time after which a hook command is killed")
+}
+
 func serviceEvents(serviceID string) {
 	// Split namespace and service
 	host, app, service, _, err := internal.ParseResourceName(serviceID)
@@ -33,6 +72,123 @@ func serviceEvents(serviceID string) {
 		os.Exit(1)
 	}
 
-	internal.EventStreamPrint("GET", fmt.Sprintf("/applications/%s/services/%s/events", app, service), nil, false)
-	internal.ExitAfterCtrlC()
+	hooks, err := newEventHooks(eventsExec, eventsPost, eventsTypes, eventsStates, eventsRetry, eventsRate, eventsTimeout)
+	internal.Check(err)
+
+	if hooks == nil {
+		internal.EventStreamPrint("GET", fmt.Sprintf("/applications/%s/services/%s/events", app, service), nil, false)
+		internal.ExitAfterCtrlC()
+		return
+	}
+
+	events := make(chan *internal.Event)
+	go SubscribeEvents(app, service, 0, events, nil, func(message string) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", message)
+	})
+	for ev := range events {
+		fmt.Fprintln(os.Stderr, ev.Message)
+		hooks.handle(ev)
+	}
+}
+
+// maximum delay between two attempts to reconnect an event stream
+const maxReconnectDelay = 30 * time.Second
+
+// SubscribeEvents sends the events of a service on events until done is closed.
+// The stream is reopened when it closes or fails, after a growing delay, and
+// warn is told why. Events older than since, a unix timestamp, are dropped and
+// it is passed to the API so that it can replay the events since then. After a
+// reconnection, since is the time of the last event received.
+func SubscribeEvents(app, service string, since float64, events chan<- *internal.Event, done <-chan struct{}, warn func(string)) {
+	delay := time.Second
+	for {
+		received, err := streamEvents(app, service, &since, events, done)
+
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		if received {
+			delay = time.Second
+		}
+		if err != nil {
+			warn(fmt.Sprintf("event stream of %s/%s lost: %s, reconnecting in %s", app, service, err, delay))
+		}
+
+		select {
+		case <-done:
+			return
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// streamEvents sends the events of a service on events until the stream closes
+// or done is closed. since is moved forward to the time of each event sent.
+// Returns whether any event was received.
+func streamEvents(app, service string, since *float64, events chan<- *internal.Event, done <-chan struct{}) (bool, error) {
+	path := fmt.Sprintf("/applications/%s/services/%s/events", app, service)
+	if *since > 0 {
+		path += "?since=" + strconv.FormatFloat(*since, 'f', -1, 64)
+	}
+
+	buffer, code, err := internal.Stream("GET", path, nil)
+	if err != nil {
+		return false, err
+	}
+	defer buffer.Close()
+
+	if code != http.StatusOK {
+		b, _ := ioutil.ReadAll(buffer)
+		if e := internal.DecodeError(b); e != nil {
+			return false, e
+		}
+		return false, fmt.Errorf("unexpected status code %d", code)
+	}
+
+	closed := make(chan struct{})
+	defer close(closed)
+	go func() {
+		select {
+		case <-done:
+			buffer.Close()
+		case <-closed:
+		}
+	}()
+
+	received := false
+	scanner := bufio.NewScanner(buffer)
+	for scanner.Scan() {
+		ev := internal.DecodeEvent(scanner.Bytes())
+		if ev == nil || ev.Event == "" {
+			continue
+		}
+		// Replayed events may have been received before a reconnection
+		if ev.Timestamp > 0 && ev.Timestamp <= *since {
+			continue
+		}
+		if ev.Timestamp > 0 {
+			*since = ev.Timestamp
+		}
+		if ev.Application == "" {
+			ev.Application = app
+		}
+		if ev.Service == "" {
+			ev.Service = service
+		}
+
+		received = true
+		select {
+		case events <- ev:
+		case <-done:
+			return received, nil
+		}
+	}
+	return received, scanner.Err()
 }
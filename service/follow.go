@@ -0,0 +1,226 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+)
+
+const (
+	// number of lines fetched at once when following logs
+	followPage = 100
+	// maximum number of pages fetched by a poll, older lines are skipped
+	followPages = 10
+)
+
+// BindFollowFlag registers --follow, with shorthand -f, on a logs command. As -f
+// is the shorthand of the global --format, --format is declared again on the
+// command without shorthand: cobra does not add a global flag to a command
+// declaring a flag of the same name.
+func BindFollowFlag(cmd *cobra.Command, follow *bool) {
+	cmd.Flags().BoolVarP(follow, "follow", "f", false, "Keep printing new lines")
+	cmd.Flags().StringVar(&internal.Format, "format", "pretty", "choose format output. One of 'json', 'yaml' and 'pretty'")
+}
+
+// FollowLogs prints the last tail lines of a logs endpoint, answering with
+// [[timestamp, id, line], ...], or its lines of the last since if not 0, then
+// polls it every interval and prints the new lines until interrupted. Failed
+// polls are retried at the next interval.
+func FollowLogs(path string, params url.Values, tail int, since, interval time.Duration) {
+	f := NewLogFollower(path, params, since)
+	out := tabwriter.NewWriter(os.Stdout, 20, 1, 3, ' ', 0)
+	print := func(lines [][]string) {
+		for _, line := range lines {
+			if internal.Format != "pretty" {
+				data, err := json.Marshal(line)
+				internal.Check(err)
+				fmt.Println(string(data))
+				continue
+			}
+			fmt.Fprintf(out, "%s\t%s\t%s\n", line[0], line[1], line[2])
+			out.Flush()
+		}
+	}
+
+	if internal.Format == "pretty" {
+		titles := []string{"TIMESTAMP", "ID", "LOG"}
+		fmt.Fprintln(out, strings.Join(titles, "\t"))
+	}
+
+	lines, err := f.Start(tail)
+	internal.Check(err)
+	print(lines)
+
+	failing := false
+	for {
+		time.Sleep(interval)
+
+		lines, err := f.Poll()
+		if err != nil {
+			if !failing {
+				fmt.Fprintf(os.Stderr, "Warning: could not fetch logs: %s, retrying every %s\n", err, interval)
+			}
+			failing = true
+			continue
+		}
+		if failing {
+			fmt.Fprintln(os.Stderr, "Logs fetched again")
+			failing = false
+		}
+		print(lines)
+	}
+}
+
+// LogFollower returns the lines of a logs endpoint not returned yet.
+//
+// Each poll fetches pages of lines going back with offsets, until it reaches
+// lines already returned. Lines are deduplicated by timestamp, ID and content.
+type LogFollower struct {
+	path   string
+	params url.Values
+	cutoff time.Time       // lines older than cutoff are not returned
+	last   string          // timestamp of the last line returned
+	seen   map[string]bool // lines returned with timestamp last
+}
+
+// NewLogFollower returns a follower of the logs endpoint at path, queried with
+// params. If since is not 0, older lines are never returned.
+func NewLogFollower(path string, params url.Values, since time.Duration) *LogFollower {
+	f := &LogFollower{
+		path:   path,
+		params: params,
+		seen:   make(map[string]bool),
+	}
+	if since > 0 {
+		f.cutoff = time.Now().Add(-since)
+	}
+	return f
+}
+
+// Start returns the lines since the cutoff, if any, or the last tail lines
+func (f *LogFollower) Start(tail int) ([][]string, error) {
+	if !f.cutoff.IsZero() {
+		return f.Poll()
+	}
+
+	lines, err := f.fetch(tail, 0)
+	if err != nil {
+		return nil, err
+	}
+	return f.fresh(lines), nil
+}
+
+func (f *LogFollower) fetch(tail, offset int) ([][]string, error) {
+	params := url.Values{}
+	for key, values := range f.params {
+		params[key] = values
+	}
+	params.Del("tail")
+	params.Del("head")
+	params.Del("offset")
+	if tail > 0 {
+		params.Set("tail", strconv.Itoa(tail))
+	}
+	if offset > 0 {
+		params.Set("offset", strconv.Itoa(offset))
+	}
+
+	var lines [][]string
+	b, err := fetch(f.path + "?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &lines); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// Poll returns the new lines, fetched a page at a time until reaching lines
+// already returned or older than the cutoff
+func (f *LogFollower) Poll() ([][]string, error) {
+	var lines [][]string
+	for page := 0; page < followPages; page++ {
+		batch, err := f.fetch(followPage, page*followPage)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(batch, lines...)
+
+		if len(batch) < followPage || f.reached(batch[0]) {
+			break
+		}
+		if page == followPages-1 {
+			fmt.Fprintf(os.Stderr, "Warning: more than %d new lines in %s, older ones are skipped\n", followPages*followPage, f.path)
+		}
+	}
+
+	return f.fresh(lines), nil
+}
+
+// reached tells whether a line was already printed or is older than the cutoff
+func (f *LogFollower) reached(line []string) bool {
+	if len(line) == 0 {
+		return false
+	}
+	if f.last != "" {
+		return line[0] <= f.last
+	}
+	return f.tooOld(line[0])
+}
+
+// tooOld tells whether a timestamp is older than the cutoff
+func (f *LogFollower) tooOld(timestamp string) bool {
+	t := logTime(timestamp)
+	return !f.cutoff.IsZero() && !t.IsZero() && t.Before(f.cutoff)
+}
+
+// fresh returns the lines not returned yet. Lines are expected in timestamp order.
+func (f *LogFollower) fresh(lines [][]string) [][]string {
+	var result [][]string
+	for _, line := range lines {
+		if len(line) < 3 {
+			continue
+		}
+		if f.tooOld(line[0]) {
+			continue
+		}
+
+		key := strings.Join(line, "\x00")
+		switch {
+		case line[0] < f.last:
+			continue
+		case line[0] == f.last:
+			if f.seen[key] {
+				continue
+			}
+		default:
+			f.last = line[0]
+			f.seen = make(map[string]bool)
+		}
+		f.seen[key] = true
+
+		result = append(result, line)
+	}
+	return result
+}
+
+// logTime parses the timestamp of a log line. Timestamps without a zone are UTC.
+// Unknown formats give the zero time.
+func logTime(timestamp string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999"} {
+		if t, err := time.Parse(layout, timestamp); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
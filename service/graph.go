@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LinkOrder sorts services so that each service comes after the services it
+// links to. Links to services which are not in specs are ignored. An error
+// describing the cycle is returned if services link to each other.
+func LinkOrder(specs map[string]ServiceSpec) ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var order []string
+	state := make(map[string]int)
+
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			// Extract the cycle from the current path
+			for i := range path {
+				if path[i] == name {
+					return fmt.Errorf("Link cycle detected: %s -> %s", strings.Join(path[i:], " -> "), name)
+				}
+			}
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, target := range sortedLinks(specs[name].Links) {
+			if _, ok := specs[target]; !ok || target == name {
+				continue
+			}
+			if err := visit(target, path); err != nil {
+				return err
+			}
+		}
+
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+func sortedLinks(links map[string]string) []string {
+	targets := make([]string, 0, len(links))
+	for target := range links {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+)
+
+var cmdServiceHistory = &cobra.Command{
+	Use:   "history",
+	Short: "List the deployments of a service: sail service history [<applicationName>/]<serviceId>",
+	Long: `List the deployments of a service: sail service history [<applicationName>/]<serviceId>
+
+Each successful add, redeploy, scale, rollback and compose up made with sail
+is recorded in a local journal, in the configuration directory. Entries hold
+the previous and the new definition of the service. Use the entry ID with
+'sail service rollback --to'.
+	"example: sail service history my-app/web"
+`,
+	Run: cmdHistory,
+}
+
+func cmdHistory(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail service history [<applicationName>/]<serviceId>. Please see sail service history --help")
+		os.Exit(1)
+	}
+
+	host, app, service, _, err := internal.ParseResourceName(args[0])
+	internal.Check(err)
+
+	if !internal.CheckHostConsistent(host) {
+		fmt.Fprintf(os.Stderr, "Error: Invalid Host %s for endpoint %s\n", host, internal.Host)
+		os.Exit(1)
+	}
+
+	entries, err := ReadJournal(app, service)
+	internal.Check(err)
+	if entries == nil {
+		entries = []JournalEntry{}
+	}
+
+	data, err := json.Marshal(entries)
+	internal.Check(err)
+
+	internal.FormatOutput(data, historyFormatter)
+}
+
+func historyFormatter(data []byte) {
+	var entries []JournalEntry
+	internal.Check(json.Unmarshal(data, &entries))
+
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "No deployment recorded for this service")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	titles := []string{"ID", "DATE", "USER", "OPERATION", "OPERATION ID", "CHANGES"}
+	fmt.Fprintln(w, strings.Join(titles, "\t"))
+
+	for _, entry := range entries {
+		operationID := entry.OperationID
+		if operationID == "" {
+			operationID = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", entry.ID, entry.Date, entry.User, entry.Operation, operationID, describeChanges(entry.Previous, entry.Next))
+	}
+	w.Flush()
+}
+
+// describeChanges summarizes the differences between 2 service definitions
+func describeChanges(previous *ServiceSpec, next ServiceSpec) string {
+	image := func(s ServiceSpec) string {
+		return s.Repository + ":" + s.RepositoryTag
+	}
+
+	if previous == nil {
+		return fmt.Sprintf("created %s, %d x %s", image(next), next.ContainerNumber, next.ContainerModel)
+	}
+
+	var changes []string
+	if image(*previous) != image(next) {
+		changes = append(changes, fmt.Sprintf("%s -> %s", image(*previous), image(next)))
+	}
+	if previous.ContainerNumber != next.ContainerNumber {
+		changes = append(changes, fmt.Sprintf("containers %d -> %d", previous.ContainerNumber, next.ContainerNumber))
+	}
+	if previous.ContainerModel != next.ContainerModel {
+		changes = append(changes, fmt.Sprintf("model %s -> %s", previous.ContainerModel, next.ContainerModel))
+	}
+
+	fields := []struct {
+		name       string
+		prev, next interface{}
+	}{
+		{"environment", previous.ContainerEnvironment, next.ContainerEnvironment},
+		{"command", previous.ContainerCommand, next.ContainerCommand},
+		{"entrypoint", previous.ContainerEntrypoint, next.ContainerEntrypoint},
+		{"user", previous.ContainerUser, next.ContainerUser},
+		{"workdir", previous.ContainerWorkdir, next.ContainerWorkdir},
+		{"restart", previous.RestartPolicy, next.RestartPolicy},
+		{"links", previous.Links, next.Links},
+		{"networks", previous.ContainerNetwork, next.ContainerNetwork},
+		{"ports", previous.ContainerPorts, next.ContainerPorts},
+		{"volumes", previous.Volumes, next.Volumes},
+		{"pool", previous.Pool, next.Pool},
+	}
+	for _, field := range fields {
+		if !reflect.DeepEqual(field.prev, field.next) {
+			changes = append(changes, field.name)
+		}
+	}
+
+	if len(changes) == 0 {
+		return "-"
+	}
+	return strings.Join(changes, ", ")
+}
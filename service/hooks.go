@@ -0,0 +1,219 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/shlex"
+
+	"github.com/runabove/sail/internal"
+)
+
+// maximum number of events waiting for their hooks, newer events are dropped
+const hookQueueSize = 100
+
+// wait after the first failure of a hook, doubled after each retry
+const hookRetryDelay = time.Second
+
+// eventHooks runs a command and posts to a URL for the events of a service
+type eventHooks struct {
+	command []string
+	url     string
+	types   []string // in lower case
+	states  []string // in lower case
+	retries int
+	rate    int           // per minute, 0 for no limit
+	timeout time.Duration // after which the command is killed
+	delay   time.Duration // before the first retry
+
+	client *http.Client
+	queue  chan *internal.Event
+	recent []time.Time // times of the events accepted in the last minute
+}
+
+// newEventHooks returns the hooks for --exec and --post, nil if none is set
+func newEventHooks(command, url string, types, states []string, retries, rate int, timeout time.Duration) (*eventHooks, error) {
+	if command == "" && url == "" {
+		return nil, nil
+	}
+
+	h := &eventHooks{
+		url:     url,
+		types:   internal.ToLower(types),
+		states:  internal.ToLower(states),
+		retries: retries,
+		rate:    rate,
+		timeout: timeout,
+		delay:   hookRetryDelay,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		queue:   make(chan *internal.Event, hookQueueSize),
+	}
+
+	if command != "" {
+		args, err := shlex.Split(command)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid command '%s': %s", command, err)
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("Invalid command '%s'", command)
+		}
+		h.command = args
+	}
+	if url != "" && !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("Invalid URL '%s'. Must start with http:// or https://", url)
+	}
+	if retries < 0 || rate < 0 {
+		return nil, fmt.Errorf("--retry and --rate must not be negative")
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("--timeout must be positive")
+	}
+
+	go h.run()
+	return h, nil
+}
+
+// matches tells whether an event passes the --type and --state filters
+func (h *eventHooks) matches(ev *internal.Event) bool {
+	if len(h.types) > 0 && !internal.StringIn(strings.ToLower(ev.Event), h.types) {
+		return false
+	}
+	if len(h.states) > 0 && !internal.StringIn(strings.ToLower(ev.State), h.states) {
+		return false
+	}
+	return true
+}
+
+// handle queues the hooks of an event, unless it is filtered out or the rate
+// limit is reached. It does not block.
+func (h *eventHooks) handle(ev *internal.Event) {
+	if !h.matches(ev) {
+		return
+	}
+
+	if h.rate > 0 {
+		now := time.Now()
+		for len(h.recent) > 0 && now.Sub(h.recent[0]) >= time.Minute {
+			h.recent = h.recent[1:]
+		}
+		if len(h.recent) >= h.rate {
+			fmt.Fprintf(os.Stderr, "Warning: more than %d events in a minute, hooks skipped for: %s\n", h.rate, ev.Message)
+			return
+		}
+		h.recent = append(h.recent, now)
+	}
+
+	select {
+	case h.queue <- ev:
+	default:
+		fmt.Fprintf(os.Stderr, "Warning: too many events waiting, hooks skipped for: %s\n", ev.Message)
+	}
+}
+
+// run calls the hooks of the queued events, one event at a time
+func (h *eventHooks) run() {
+	for ev := range h.queue {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not encode event: %s\n", err)
+			continue
+		}
+
+		if h.command != nil {
+			h.retry("command", func() error { return h.exec(ev, data) })
+		}
+		if h.url != "" {
+			h.retry("post", func() error { return h.post(data) })
+		}
+	}
+}
+
+// retry calls hook until it succeeds, at most --retry more times, waiting
+// longer after each failure
+func (h *eventHooks) retry(name string, hook func() error) {
+	delay := h.delay
+	for attempt := 0; ; attempt++ {
+		err := hook()
+		if err == nil {
+			return
+		}
+		if attempt >= h.retries {
+			fmt.Fprintf(os.Stderr, "Error: %s hook failed: %s, giving up\n", name, err)
+			return
+		}
+
+		fmt.Fprintf(os.Stderr, "Warning: %s hook failed: %s, retrying in %s\n", name, err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// exec runs the command with the event as JSON on stdin and described by
+// SAIL_* environment variables. The command is killed after --timeout.
+func (h *eventHooks) exec(ev *internal.Event, data []byte) error {
+	cmd := exec.Command(h.command[0], h.command[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), eventEnv(ev)...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(h.timeout):
+		cmd.Process.Kill()
+		<-done
+		return fmt.Errorf("command killed after %s", h.timeout)
+	}
+}
+
+// eventEnv returns the environment variables describing an event
+func eventEnv(ev *internal.Event) []string {
+	env := []string{
+		"SAIL_EVENT=" + ev.Event,
+		"SAIL_STATE=" + ev.State,
+		"SAIL_PREV_STATE=" + ev.PrevState,
+		"SAIL_APPLICATION=" + ev.Application,
+		"SAIL_SERVICE=" + ev.Service,
+		"SAIL_CONTAINER=" + ev.ID,
+		"SAIL_MESSAGE=" + ev.Message,
+	}
+
+	if ev.Data != nil && ev.Data.LastExitStatus != nil {
+		status := ev.Data.LastExitStatus
+		if status.ExitStatus != nil {
+			env = append(env, "SAIL_EXIT_STATUS="+strconv.Itoa(*status.ExitStatus))
+		}
+		if status.Signal != nil {
+			env = append(env, "SAIL_SIGNAL="+strconv.Itoa(*status.Signal))
+		}
+	}
+	return env
+}
+
+// post posts the event as JSON to the URL, which must answer with a 2xx status
+func (h *eventHooks) post(data []byte) error {
+	resp, err := h.client.Post(h.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s answered %s", h.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runabove/sail/internal"
+)
+
+func newTestHooks(url string, retries, rate int) *eventHooks {
+	return &eventHooks{
+		url:     url,
+		retries: retries,
+		rate:    rate,
+		timeout: time.Second,
+		delay:   time.Millisecond,
+		client:  &http.Client{Timeout: time.Second},
+		queue:   make(chan *internal.Event, hookQueueSize),
+	}
+}
+
+func TestHooksPost(t *testing.T) {
+	// Answers with the status given as path
+	var body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		status, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if r.Header.Get("Content-Type") != "application/json" {
+			status = http.StatusBadRequest
+		}
+		w.WriteHeader(status)
+	}))
+	defer s.Close()
+
+	tests := []struct {
+		status int
+		err    bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusNoContent, false},
+		{http.StatusNotModified, true},
+		{http.StatusInternalServerError, true},
+	}
+
+	for _, test := range tests {
+		h := newTestHooks(fmt.Sprintf("%s/%d", s.URL, test.status), 0, 0)
+		body = ""
+
+		err := h.post([]byte(`{"event":"state"}`))
+		if test.err && err == nil {
+			t.Errorf("%d: expected an error", test.status)
+		} else if !test.err && err != nil {
+			t.Errorf("%d: %s", test.status, err)
+		}
+		if body != `{"event":"state"}` {
+			t.Errorf("%d: posted %q", test.status, body)
+		}
+	}
+}
+
+func TestHooksRetry(t *testing.T) {
+	tests := []struct {
+		failures int
+		retries  int
+		calls    int
+	}{
+		{0, 3, 1},
+		{2, 3, 3},
+		{5, 3, 4},
+		{1, 0, 1},
+	}
+
+	for _, test := range tests {
+		h := newTestHooks("", test.retries, 0)
+
+		calls := 0
+		h.retry("test", func() error {
+			calls++
+			if calls <= test.failures {
+				return fmt.Errorf("failure %d", calls)
+			}
+			return nil
+		})
+		if calls != test.calls {
+			t.Errorf("%d failures with %d retries: called %d times, want %d", test.failures, test.retries, calls, test.calls)
+		}
+	}
+}
+
+func TestHooksRate(t *testing.T) {
+	h := newTestHooks("http://127.0.0.1/", 0, 2)
+
+	for i := 0; i < 3; i++ {
+		h.handle(&internal.Event{Event: "state", Message: "event"})
+	}
+	if len(h.queue) != 2 {
+		t.Errorf("queued %d events, want 2", len(h.queue))
+	}
+
+	// Events older than a minute do not count anymore
+	h.recent[0] = time.Now().Add(-time.Minute)
+	h.handle(&internal.Event{Event: "state", Message: "event"})
+	if len(h.queue) != 3 {
+		t.Errorf("queued %d events, want 3", len(h.queue))
+	}
+}
+
+func TestHooksFilter(t *testing.T) {
+	h := newTestHooks("http://127.0.0.1/", 0, 0)
+	h.types = []string{"state"}
+	h.states = []string{"stopped"}
+
+	h.handle(&internal.Event{Event: "state", State: "STOPPED"})
+	h.handle(&internal.Event{Event: "state", State: "RUNNING"})
+	h.handle(&internal.Event{Event: "scale", State: "STOPPED"})
+	if len(h.queue) != 1 {
+		t.Errorf("queued %d events, want 1", len(h.queue))
+	}
+}
+
+func TestHooksExecTimeout(t *testing.T) {
+	h := newTestHooks("", 0, 0)
+	h.timeout = 100 * time.Millisecond
+
+	h.command = []string{"sh", "-c", "exit 0"}
+	if err := h.exec(&internal.Event{}, []byte("{}")); err != nil {
+		t.Errorf("exit 0: %s", err)
+	}
+
+	h.command = []string{"sleep", "5"}
+	start := time.Now()
+	err := h.exec(&internal.Event{}, []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "killed") {
+		t.Errorf("sleep 5: expected the command to be killed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("sleep 5: returned after %s", elapsed)
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/runabove/sail/internal"
+)
+
+// journalSize is the number of entries kept per service
+const journalSize = 100
+
+// JournalEntry records a successful change of a service definition
+type JournalEntry struct {
+	ID          int          `json:"id"`
+	Date        string       `json:"date"`
+	User        string       `json:"user"`
+	Operation   string       `json:"operation"`
+	OperationID string       `json:"operation_id,omitempty"`
+	Previous    *ServiceSpec `json:"previous,omitempty"`
+	Next        ServiceSpec  `json:"next"`
+}
+
+// journalPath returns the local journal file of a service. Journals are
+// stored per endpoint in the configuration directory.
+func journalPath(app, service string) string {
+	host := strings.NewReplacer("://", "_", "/", "_", ":", "_").Replace(internal.Host)
+	return filepath.Join(internal.ConfigDir, "sail", "history", host, app, service+".json")
+}
+
+// ReadJournal returns the journal of a service, oldest entry first
+func ReadJournal(app, service string) ([]JournalEntry, error) {
+	var entries []JournalEntry
+
+	data, err := ioutil.ReadFile(journalPath(app, service))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %s", journalPath(app, service), err)
+	}
+	return entries, nil
+}
+
+// journaled runs change, a change of a service, and records it in the journal.
+// Failing to record it is not fatal: it is passed to report as a warning.
+func journaled(operation, app, service string, report func(string), change func() (string, error)) (string, error) {
+	previous, err := journalPrevious(app, service)
+	if err != nil {
+		report(fmt.Sprintf("Warning: could not record deployment history: %s", err))
+	}
+
+	hostname, err := change()
+	if err != nil || previous == nil {
+		return hostname, err
+	}
+
+	if err := journalRecord(operation, previous, app, service); err != nil {
+		report(fmt.Sprintf("Warning: could not record deployment history: %s", err))
+	}
+	return hostname, nil
+}
+
+// journalRecord fetches the current definition of a service and appends it to
+// the journal
+func journalRecord(operation string, previous *ServiceSpec, app, service string) error {
+	next, err := fetchServiceSpec(app, service)
+	if err != nil {
+		return err
+	}
+	return appendJournal(operation, previous, next)
+}
+
+// journalPrevious returns the current definition of a service, to be recorded
+// as the previous definition of the next journal entry
+func journalPrevious(app, service string) (*ServiceSpec, error) {
+	spec, err := fetchServiceSpec(app, service)
+	if err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+func appendJournal(operation string, previous *ServiceSpec, next ServiceSpec) error {
+	entries, err := ReadJournal(next.Application, next.Service)
+	if err != nil {
+		return err
+	}
+
+	entry := JournalEntry{
+		ID:          1,
+		Date:        time.Now().UTC().Format(time.RFC3339),
+		User:        internal.User,
+		Operation:   operation,
+		OperationID: lastOperationID(next.Application, next.Service),
+		Previous:    previous,
+		Next:        next,
+	}
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+
+	entries = append(entries, entry)
+	if len(entries) > journalSize {
+		entries = entries[len(entries)-journalSize:]
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := journalPath(next.Application, next.Service)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	// Write then rename so that an interrupted write never corrupts the journal
+	if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// lastOperationID returns the ID of the most recent operation on a service, if any
+func lastOperationID(app, service string) string {
+	var operations []map[string]string
+
+	b, code, err := internal.Request("GET", fmt.Sprintf("/operation/application/%s", app), nil)
+	if err != nil || code != http.StatusOK || json.Unmarshal(b, &operations) != nil {
+		return ""
+	}
+
+	var id, started string
+	for _, operation := range operations {
+		if operation["service"] == service && operation["started_at"] >= started {
+			id, started = operation["topic"], operation["started_at"]
+		}
+	}
+	return id
+}
+
+// Snapshot returns the definitions of all services of an application
+func Snapshot(app string) (map[string]ServiceSpec, error) {
+	var services []string
+	if err := internal.GetJSON(fmt.Sprintf("/applications/%s/services", app), &services); err != nil {
+		return nil, err
+	}
+
+	specs := make(map[string]ServiceSpec)
+	for _, name := range services {
+		spec, err := fetchServiceSpec(app, name)
+		if err != nil {
+			return nil, err
+		}
+		specs[name] = spec
+	}
+	return specs, nil
+}
+
+// RecordApply records in the deployment journal the services whose definition
+// changed since before was taken with Snapshot.
+func RecordApply(app string, before map[string]ServiceSpec, services []string) {
+	for _, service := range services {
+		next, err := fetchServiceSpec(app, service)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not record deployment history: %s\n", err)
+			continue
+		}
+
+		var previous *ServiceSpec
+		if spec, ok := before[service]; ok {
+			if SameDefinition(spec, next) {
+				continue
+			}
+			previous = &spec
+		}
+
+		if err := appendJournal("apply", previous, next); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not record deployment history: %s\n", err)
+		}
+	}
+}
+
+// SameDefinition tells whether 2 service definitions are identical
+func SameDefinition(a, b ServiceSpec) bool {
+	ja, err := json.Marshal(a)
+	internal.Check(err)
+	jb, err := json.Marshal(b)
+	internal.Check(err)
+	return string(ja) == string(jb)
+}
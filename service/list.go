@@ -1,29 +1,81 @@
 package service
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/runabove/sail/internal"
 	"github.com/spf13/cobra"
 )
 
+var (
+	listWatch    bool
+	listInterval time.Duration
+)
+
 var cmdServiceList = &cobra.Command{
-	Use:     "list",
-	Short:   "List the docker services: sail service list [applicationName]",
+	Use:   "list",
+	Short: "List the docker services: sail service list [applicationName] [--watch]",
+	Long: `List the docker services: sail service list [applicationName] [--watch]
+
+With --watch, the list is fetched every --interval and printed again whenever it
+changes, preceded by the time of the change. See 'sail top' for an interactive view.`,
 	Aliases: []string{"ls", "ps"},
 	Run: func(cmd *cobra.Command, args []string) {
-		serviceList(internal.GetListApplications(args))
+		apps := internal.GetListApplications(args)
+		if listWatch {
+			watchServiceList(apps, listInterval)
+			return
+		}
+		serviceList(apps)
 	},
 }
 
+func init() {
+	cmdServiceList.Flags().BoolVar(&listWatch, "watch", false, "print the list again whenever it changes")
+	cmdServiceList.Flags().DurationVar(&listInterval, "interval", 2*time.Second, "time between two fetches with --watch")
+}
+
 func serviceList(apps []string) {
-	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	writeServiceList(os.Stdout, apps)
+}
+
+// watchServiceList prints the service list each time it changes. It only uses
+// line output, so that it works on any terminal and in logs.
+func watchServiceList(apps []string, interval time.Duration) {
+	if interval < time.Second {
+		internal.Exit("Error: --interval must be at least 1s\n")
+	}
+
+	var previous []byte
+	for {
+		var buffer bytes.Buffer
+		writeServiceList(&buffer, apps)
+
+		if !bytes.Equal(buffer.Bytes(), previous) {
+			if previous != nil {
+				fmt.Println()
+			}
+			fmt.Printf("Every %s: %s\n\n", interval, time.Now().Format("2006-01-02 15:04:05"))
+			os.Stdout.Write(buffer.Bytes())
+			previous = buffer.Bytes()
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// writeServiceList writes the service table of apps to out
+func writeServiceList(out io.Writer, apps []string) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
 	titles := []string{"NAME", "REPOSITORY", "IMAGE ID", "STATE", "CONTAINERS", "CREATED", "NETWORK"}
 	fmt.Fprintln(w, strings.Join(titles, "\t"))
 
@@ -47,6 +99,7 @@ func serviceList(apps []string) {
 					ips = append(ips, fmt.Sprintf("%s:%s", name, network.(map[string]interface{})["ip"]))
 				}
 			}
+			sort.Strings(ips)
 
 			fmt.Fprintf(w, "%s/%s\t%s@%s\t%s\t%s\t%d\t%s\t%s\n",
 				app, service["name"],
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/google/go-querystring/query"
 	"github.com/runabove/sail/internal"
@@ -13,15 +14,26 @@ import (
 )
 
 var (
-	logsBody Logs
+	logsBody     Logs
+	logsFollow   bool
+	logsSince    time.Duration
+	logsInterval time.Duration
 )
 
 func logsCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "logs",
-		Short: "Logs of a docker service: sail service logs [<applicationName>/]<serviceId>",
-		Long:  `Logs of a docker service: sail service logs [<applicationName>/]<serviceId>`,
-		Run:   cmdLogs,
+		Short: "Logs of a docker service: sail service logs [<applicationName>/]<serviceId> [--follow]",
+		Long: `Logs of a docker service: sail service logs [<applicationName>/]<serviceId> [--follow]
+
+With --follow, the logs are polled every --interval and new lines are printed
+until interrupted. Polls resume after transient errors. --since prints the lines
+of the last period first, instead of the --tail last ones. With --format json,
+lines are printed as one JSON array per line. On this command, -f is the
+shorthand of --follow, not of --format.
+	"example: sail service logs my-app/web --follow --since 10m"
+`,
+		Run: cmdLogs,
 	}
 
 	cmd.Flags().IntVarP(&logsBody.Tail, "tail", "", 0, "Return N last lines, before offset")
@@ -29,6 +41,9 @@ func logsCmd() *cobra.Command {
 	cmd.Flags().IntVarP(&logsBody.Offset, "offset", "", 0, "Offset result by N line")
 	cmd.Flags().StringVarP(&logsBody.Period, "period", "", "", "Human readable (Lucene syntax) period")
 	cmd.Flags().StringVarP(&logsBody.Search, "search", "", "", "Only return matching lines")
+	BindFollowFlag(cmd, &logsFollow)
+	cmd.Flags().DurationVar(&logsSince, "since", 0, "With --follow, first print the lines of this last period")
+	cmd.Flags().DurationVar(&logsInterval, "interval", 2*time.Second, "With --follow, time between two polls")
 
 	return cmd
 }
@@ -49,7 +64,7 @@ type Logs struct {
 func cmdLogs(cmd *cobra.Command, args []string) {
 	usage := "Invalid usage. sail service logs [<applicationName>/]<serviceId>. Please see sail service logs --help\n"
 	if len(args) != 1 {
-		fmt.Fprintf(os.Stderr, usage)
+		fmt.Fprint(os.Stderr, usage)
 		return
 	}
 
@@ -65,9 +80,32 @@ func cmdLogs(cmd *cobra.Command, args []string) {
 	// Get args
 	logsBody.Application = app
 	logsBody.Service = service
+
+	if logsFollow {
+		followServiceLogs(logsBody)
+		return
+	}
+	if logsSince > 0 {
+		internal.Exit("Error: --since can only be used with --follow\n")
+	}
 	serviceLogs(logsBody)
 }
 
+func followServiceLogs(args Logs) {
+	if args.Head > 0 || args.Offset > 0 {
+		internal.Exit("Error: --head and --offset can not be used with --follow\n")
+	}
+	if logsInterval < time.Second {
+		internal.Exit("Error: --interval must be at least 1s\n")
+	}
+
+	params, err := query.Values(args)
+	internal.Check(err)
+
+	path := fmt.Sprintf("/applications/%s/services/%s/logs", args.Application, args.Service)
+	FollowLogs(path, params, args.Tail, logsSince, logsInterval)
+}
+
 func serviceLogs(args Logs) {
 
 	queryArgs, err := query.Values(args)
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// portRange is an inclusive range of port numbers. A single port is a range of size 1
+type portRange struct {
+	first int
+	last  int
+}
+
+func (r portRange) size() int {
+	return r.last - r.first + 1
+}
+
+func parsePort(raw string) (int, error) {
+	port, err := strconv.Atoi(raw)
+	if err != nil || port < 1 || port > 65535 {
+		return -1, fmt.Errorf("Invalid port number '%s': should be between 1 and 65535", raw)
+	}
+	return port, nil
+}
+
+// parsePortRange parses port[-port]
+func parsePortRange(raw string) (portRange, error) {
+	bounds := strings.SplitN(raw, "-", 2)
+
+	first, err := parsePort(bounds[0])
+	if err != nil {
+		return portRange{}, err
+	}
+	r := portRange{first: first, last: first}
+
+	if len(bounds) == 2 {
+		r.last, err = parsePort(bounds[1])
+		if err != nil {
+			return portRange{}, err
+		}
+		if r.last < r.first {
+			return portRange{}, fmt.Errorf("Invalid port range '%s': %d is lower than %d", raw, r.last, r.first)
+		}
+	}
+
+	return r, nil
+}
+
+// isPortRange tells whether raw looks like port[-port], as opposed to a network name
+func isPortRange(raw string) bool {
+	if raw == "" {
+		return false
+	}
+	for _, c := range raw {
+		if (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// splitProtocol splits port[/protocol]. protocol is empty when not specified
+func splitProtocol(raw string) (string, string, error) {
+	split := strings.Split(raw, "/")
+	if len(split) == 1 {
+		return split[0], "", nil
+	} else if len(split) == 2 && (split[1] == "tcp" || split[1] == "udp") {
+		return split[0], split[1], nil
+	}
+	return "", "", fmt.Errorf("Invalid port '%s': protocol should be one of 'tcp', 'udp'", raw)
+}
+
+func parsePublishedPort(args []string) (map[string][]PortConfig, error) {
+	var errs validationErrors
+	v := make(map[string][]PortConfig)
+
+	for _, pub := range args {
+		errs.add(parsePublishRule(pub, v))
+	}
+
+	return v, errs.err()
+}
+
+// parsePublishRule parses a single --publish rule and expands it into v
+func parsePublishRule(pub string, v map[string][]PortConfig) error {
+	var network, published string
+
+	split := strings.Split(pub, ":")
+	switch len(split) {
+	case 1: // containerPort
+	case 2: // network:containerPort, publishedPort:containerPort
+		if isPortRange(split[0]) {
+			published = split[0]
+		} else {
+			network = split[0]
+		}
+	case 3: // network:publishedPort:containerPort, network::containerPort
+		network = split[0]
+		published = split[1]
+	default:
+		return fmt.Errorf("Invalid port expose rule '%s'", pub)
+	}
+
+	containerPorts, protocol, err := splitProtocol(split[len(split)-1])
+	if err != nil {
+		return err
+	}
+	if protocol == "" {
+		protocol = "tcp"
+	}
+
+	container, err := parsePortRange(containerPorts)
+	if err != nil {
+		return err
+	}
+
+	public := container
+	if published != "" {
+		public, err = parsePortRange(published)
+		if err != nil {
+			return err
+		}
+	}
+
+	if public.size() != container.size() {
+		return fmt.Errorf("Invalid port expose rule '%s': published and container port ranges must have the same size", pub)
+	}
+
+	// Expand ranges into one entry per port
+	for i := 0; i < container.size(); i++ {
+		key := fmt.Sprintf("%d/%s", container.first+i, protocol)
+		v[key] = append(v[key], PortConfig{PublishedPort: public.first + i, Network: network})
+	}
+
+	return nil
+}
+
+func parseWhitelistedCidrs(args []string, containerPorts map[string][]PortConfig) (map[string][]PortConfig, error) {
+	var errs validationErrors
+
+	// Parse NetworkAllow
+	for _, network := range args {
+		errs.add(parseWhitelistRule(network, containerPorts))
+	}
+
+	return containerPorts, errs.err()
+}
+
+// splitWhitelistRule splits a --network-allow rule into its address and its
+// ports, empty if not given. IPv6 addresses are followed by ports only when
+// enclosed in brackets: [2001:db8::/32]:80.
+func splitWhitelistRule(network string) (string, string, error) {
+	invalid := fmt.Errorf("Invalid allowed network '%s', should be 1.2.3.4[/24][:80[-90][/udp]], IPv6 addresses followed by ports in brackets: [2001:db8::/32]:80", network)
+
+	if strings.HasPrefix(network, "[") {
+		end := strings.Index(network, "]")
+		if end < 0 {
+			return "", "", invalid
+		}
+		addr, rest := network[1:end], network[end+1:]
+		if rest == "" {
+			return addr, "", nil
+		}
+		if !strings.HasPrefix(rest, ":") || len(rest) == 1 {
+			return "", "", invalid
+		}
+		return addr, rest[1:], nil
+	}
+
+	// Unbracketed IPv6 address, without ports
+	if strings.Count(network, ":") > 1 {
+		return network, "", nil
+	}
+
+	split := strings.SplitN(network, ":", 2)
+	if len(split) == 1 {
+		return network, "", nil
+	}
+	if split[1] == "" {
+		return "", "", invalid
+	}
+	return split[0], split[1], nil
+}
+
+// parseWhitelistRule parses a single --network-allow rule and applies it to containerPorts
+func parseWhitelistRule(network string, containerPorts map[string][]PortConfig) error {
+	addr, portRange, err := splitWhitelistRule(network)
+	if err != nil {
+		return err
+	}
+	if err := checkCidr(addr); err != nil {
+		return err
+	}
+
+	if portRange == "" {
+		// No port specified, applying to all exposed ports
+		for port := range containerPorts {
+			for portConfig := range containerPorts[port] {
+				containerPorts[port][portConfig].WhitelistedCidrs = append(containerPorts[port][portConfig].WhitelistedCidrs, addr)
+			}
+		}
+		return nil
+	}
+
+	// Apply to specified ports. Without protocol, apply to both tcp and udp
+	ports, protocol, err := splitProtocol(portRange)
+	if err != nil {
+		return err
+	}
+	r, err := parsePortRange(ports)
+	if err != nil {
+		return err
+	}
+
+	protocols := []string{protocol}
+	if protocol == "" {
+		protocols = []string{"tcp", "udp"}
+	}
+
+	for i := 0; i < r.size(); i++ {
+		found := false
+		for _, proto := range protocols {
+			port := fmt.Sprintf("%d/%s", r.first+i, proto)
+			for portConfig := range containerPorts[port] {
+				containerPorts[port][portConfig].WhitelistedCidrs = append(containerPorts[port][portConfig].WhitelistedCidrs, addr)
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("Invalid allowed network '%s': port %d is not published", network, r.first+i)
+		}
+	}
+
+	return nil
+}
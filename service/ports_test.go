@@ -0,0 +1,57 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWhitelistRule(t *testing.T) {
+	tests := []struct {
+		rule string
+		http []string // whitelisted CIDRs of 80/tcp
+		dns  []string // whitelisted CIDRs of 53/udp
+		err  bool
+	}{
+		{"1.2.3.4", []string{"1.2.3.4"}, []string{"1.2.3.4"}, false},
+		{"10.0.0.0/8:80", []string{"10.0.0.0/8"}, nil, false},
+		{"10.0.0.0/8:53/udp", nil, []string{"10.0.0.0/8"}, false},
+		{"2001:db8::1", []string{"2001:db8::1"}, []string{"2001:db8::1"}, false},
+		{"2001:db8::/32", []string{"2001:db8::/32"}, []string{"2001:db8::/32"}, false},
+		{"[2001:db8::/32]", []string{"2001:db8::/32"}, []string{"2001:db8::/32"}, false},
+		{"[2001:db8::/32]:80", []string{"2001:db8::/32"}, nil, false},
+		{"[::1]:53/udp", nil, []string{"::1"}, false},
+		{"1.2.3.4:", nil, nil, true},
+		{"1.2.3.4:8080", nil, nil, true},
+		{"1.2.3.4/33", nil, nil, true},
+		{"[2001:db8::/32]80", nil, nil, true},
+		{"[2001:db8::/32", nil, nil, true},
+		{"[2001:db8::/32]:", nil, nil, true},
+		{"2001:db8::/129", nil, nil, true},
+	}
+
+	for _, test := range tests {
+		ports := map[string][]PortConfig{
+			"80/tcp": {{PublishedPort: 80}},
+			"53/udp": {{PublishedPort: 53}},
+		}
+
+		err := parseWhitelistRule(test.rule, ports)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.rule, err)
+			continue
+		}
+
+		if http := ports["80/tcp"][0].WhitelistedCidrs; !reflect.DeepEqual(http, test.http) {
+			t.Errorf("%s: 80/tcp allows %v, want %v", test.rule, http, test.http)
+		}
+		if dns := ports["53/udp"][0].WhitelistedCidrs; !reflect.DeepEqual(dns, test.dns) {
+			t.Errorf("%s: 53/udp allows %v, want %v", test.rule, dns, test.dns)
+		}
+	}
+}
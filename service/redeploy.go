@@ -4,52 +4,46 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"strings"
+	"time"
 
-	"github.com/google/shlex"
 	"github.com/spf13/cobra"
 
 	"github.com/runabove/sail/internal"
 )
 
 var (
-	redeployBody         Redeploy
-	redeployPublished    []string
-	redeployLink         []string
-	redeployNetwork      []string
-	redeployNetworkAllow []string
-	redeployVolume       []string
-	redeployBatch        bool
-	redeployPool         string
-	redeployCommand      string
-	redeployEntrypoint   string
+	redeploySpec     specFlags
+	redeployBatch    bool
+	redeployWait     bool
+	redeployTimeout  time.Duration
+	redeployRollback bool
 )
 
 func redeployCmd() *cobra.Command {
 
 	cmd := &cobra.Command{
-		Use:     "redeploy",
-		Short:   "Redeploy a docker service: sail service redeploy [<applicationName>/]<serviceId>",
-		Long:    `Redeploy a docker service: sail service redeploy [<applicationName>/]<serviceId>`,
+		Use:   "redeploy",
+		Short: "Redeploy a docker service: sail service redeploy [<applicationName>/]<serviceId>...",
+		Long: `Redeploy a docker service: sail service redeploy [<applicationName>/]<serviceId>...
+
+With --wait, sail waits until all the containers of the service are running.
+All the containers are replaced at once, the API cannot replace them in
+batches. The rollout is aborted when the containers are not running within
+--timeout (exit status 3) or when a container keeps crashing (exit status 4).
+With --rollback, the previous definition is then redeployed.
+
+` + selectorUsage + `
+--wait applies to a single service only.`,
 		Aliases: []string{"restart"},
 		Run:     cmdRedeploy,
 	}
 
-	cmd.Flags().StringVarP(&redeployBody.ContainerModel, "model", "", "", "Container model")
-	cmd.Flags().StringSliceVarP(&redeployBody.ContainerEnvironment, "env", "e", nil, "override docker environment. Syntax: key=val,...")
-	cmd.Flags().StringVarP(&redeployBody.RestartPolicy, "restart", "", "", "{no|always[:<max>]|on-failure[:<max>]}")
-	cmd.Flags().StringVarP(&redeployCommand, "command", "", "", "override docker run command")
-	cmd.Flags().StringVarP(&redeployBody.RepositoryTag, "tag", "", "", "deploy from new image version")
-	cmd.Flags().StringVarP(&redeployBody.ContainerWorkdir, "workdir", "", "", "override docker workdir")
-	cmd.Flags().StringVarP(&redeployEntrypoint, "entrypoint", "", "", "override docker entrypoint")
-	cmd.Flags().StringVarP(&redeployBody.ContainerUser, "user", "", "", "override docker user")
-	cmd.Flags().StringSliceVar(&redeployNetwork, "network", nil, "public|private|<namespace name>")
-	cmd.Flags().StringSliceVar(&redeployNetworkAllow, "network-allow", []string{}, "[network:]ip[/mask] Use IPs whitelist")
-	cmd.Flags().StringSliceVarP(&redeployPublished, "publish", "p", nil, "Publish a container's port to the host")
-	cmd.Flags().StringSliceVarP(&redeployVolume, "volume", "", nil, "/path:size] (Size in GB)")
+	redeploySpec.bind(cmd, false)
 	cmd.Flags().BoolVarP(&redeployBatch, "batch", "", false, "do not attach console on start")
-	cmd.Flags().StringSliceVarP(&redeployLink, "link", "", nil, "name:alias")
-	cmd.Flags().StringVarP(&redeployPool, "pool", "", "", "Dedicated host pool")
+	cmd.Flags().BoolVar(&redeployWait, "wait", false, "wait until the containers are running and abort the rollout on failure")
+	cmd.Flags().DurationVar(&redeployTimeout, "timeout", 5*time.Minute, "with --wait, maximum time for the containers to be running")
+	cmd.Flags().BoolVar(&redeployRollback, "rollback", false, "with --wait, redeploy the previous definition when the rollout is aborted")
+	bulk.bind(cmd)
 	return cmd
 }
 
@@ -63,6 +57,7 @@ type Redeploy struct {
 	ContainerCommand     []string                       `json:"container_command,omitempty"`
 	ContainerNetwork     map[string]map[string][]string `json:"container_network,omitempty"`
 	ContainerEntrypoint  []string                       `json:"container_entrypoint,omitempty"`
+	ContainerNumber      int                            `json:"container_number,omitempty"`
 	RepositoryTag        string                         `json:"repository_tag,omitempty"`
 	Links                map[string]string              `json:"links,omitempty"`
 	Application          string                         `json:"namespace,omitempty"`
@@ -73,105 +68,81 @@ type Redeploy struct {
 	Pool                 string                         `json:"pool,omitempty"`
 }
 
+// FullRedeploy struct holds a complete definition sent to
+// /applications/%s/services/%s/redeploy, empty fields included
+type FullRedeploy struct {
+	Volumes              map[string]VolumeConfig        `json:"volumes"`
+	Repository           string                         `json:"repository"`
+	ContainerUser        string                         `json:"container_user"`
+	RestartPolicy        string                         `json:"restart_policy"`
+	ContainerCommand     []string                       `json:"container_command"`
+	ContainerNetwork     map[string]map[string][]string `json:"container_network"`
+	ContainerEntrypoint  []string                       `json:"container_entrypoint"`
+	ContainerNumber      int                            `json:"container_number"`
+	RepositoryTag        string                         `json:"repository_tag"`
+	Links                map[string]string              `json:"links"`
+	Application          string                         `json:"namespace"`
+	ContainerWorkdir     string                         `json:"container_workdir"`
+	ContainerEnvironment []string                       `json:"container_environment"`
+	ContainerModel       string                         `json:"container_model"`
+	ContainerPorts       map[string][]PortConfig        `json:"container_ports"`
+	Pool                 string                         `json:"pool"`
+}
+
 func cmdRedeploy(cmd *cobra.Command, args []string) {
-	usage := "Invalid usage. sail service redeploy [<applicationName>/]<serviceId>. Please see sail service redeploy --help\n"
-	if len(args) != 1 {
-		fmt.Fprintf(os.Stderr, usage)
+	usage := "Invalid usage. sail service redeploy [<applicationName>/]<serviceId>... Please see sail service redeploy --help\n"
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return
+	}
+
+	if bulk.active(args) {
+		if redeployWait {
+			internal.Exit("Error: --wait can only be used with a single service\n")
+		}
+		bulk.run(args, "Redeploy", func(s selected, report func(string)) (string, error) {
+			spec := ServiceSpec{Application: s.app, Service: s.service}
+			if err := redeploySpec.parse(&spec); err != nil {
+				return "", err
+			}
+			body, err := json.Marshal(spec.Redeploy())
+			if err != nil {
+				return "", err
+			}
+			path := fmt.Sprintf("/applications/%s/services/%s/redeploy", s.app, s.service)
+			_, err = redeployService(path, body, s.app, s.service, "redeploy", report)
+			return "", err
+		})
 		return
 	}
 
 	// Split namespace and repository
 	host, app, service, _, err := internal.ParseResourceName(args[0])
 	internal.Check(err)
-	redeployBody.Application = app
-	redeployBody.Service = service
+	spec := ServiceSpec{
+		Application: app,
+		Service:     service,
+	}
 
 	if !internal.CheckHostConsistent(host) {
 		fmt.Fprintf(os.Stderr, "Error: Invalid Host %s for endpoint %s\n", host, internal.Host)
 		os.Exit(1)
 	}
 
-	// Redeploy
-	serviceRedeploy(redeployBody)
-}
-
-func serviceRedeploy(args Redeploy) {
-
-	// Parse volumes
-	if len(redeployVolume) > 0 {
-		args.Volumes = make(map[string]VolumeConfig)
-	}
-
-	// Parse command
-	if redeployCommand != "" {
-		command, err := shlex.Split(redeployCommand)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Fatal, cannot split command %s\n", err)
-			return
-		}
-		args.ContainerCommand = command
-	}
+	internal.Check(redeploySpec.parse(&spec))
 
-	// Parse Entrypoint
-	if redeployEntrypoint != "" {
-		entrypoint, err := shlex.Split(redeployEntrypoint)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Fatal, cannot split command %s\n", err)
-			return
-		}
-		args.ContainerEntrypoint = entrypoint
-	}
-
-	for _, vol := range redeployVolume {
-		t := strings.Split(vol, ":")
-		if len(t) == 2 {
-			args.Volumes[t[0]] = VolumeConfig{Size: t[1]}
-		} else if len(t) == 1 {
-			args.Volumes[t[0]] = VolumeConfig{Size: "10"}
-		} else {
-			fmt.Fprintf(os.Stderr, "Error: Volume parameter '%s' not formated correctly\n", vol)
-			os.Exit(1)
-		}
-	}
-
-	// Parse links
-	if len(redeployLink) > 0 {
-		args.Links = make(map[string]string)
-	}
-
-	for _, link := range redeployLink {
-		t := strings.Split(link, ":")
-		if len(t) == 1 {
-			args.Links[t[0]] = t[0]
-		} else {
-			args.Links[t[0]] = t[1]
-		}
-	}
-
-	// Parse ContainerNetworks arguments
-	if len(redeployNetwork) > 0 {
-		args.ContainerNetwork = make(map[string]map[string][]string)
-	}
-	for _, network := range redeployNetwork {
-		args.ContainerNetwork[network] = make(map[string][]string)
+	// Redeploy
+	if redeployWait {
+		waitRedeploy(spec.Redeploy(), app, service)
+	} else {
+		doServiceRedeploy(spec.Redeploy(), app, service, "redeploy", redeployBatch)
 	}
-
-	// Load Pool
-	args.Pool = redeployPool
-
-	// Parse ContainerPorts
-	args.ContainerPorts = parsePublishedPort(redeployPublished)
-	app := args.Application
-	service := args.Service
-
-	// Parse NetworkAllow
-	args.ContainerPorts = parseWhitelistedCidrs(redeployNetworkAllow, args.ContainerPorts)
-
-	// Actual redeploy
-	doServiceRedeploy(args, app, service)
 }
 
-func doServiceRedeploy(args Redeploy, app, service string) {
+// doServiceRedeploy redeploys a service with args, a Redeploy or FullRedeploy
+// body, and records the change in the deployment journal under operation. The
+// console is attached unless batch is set.
+func doServiceRedeploy(args interface{}, app, service, operation string, batch bool) {
 	path := fmt.Sprintf("/applications/%s/services/%s/redeploy", app, service)
 	body, err := json.MarshalIndent(args, " ", " ")
 	if err != nil {
@@ -180,28 +151,36 @@ func doServiceRedeploy(args Redeploy, app, service string) {
 	}
 
 	// Attach console
-	if !redeployBatch {
+	if !batch {
 		internal.StreamPrint("GET", fmt.Sprintf("/applications/%s/services/%s/attach", app, service), nil)
 	}
 
 	// Redeploy
-	buffer, _, err := internal.Stream("POST", path, body)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(1)
-	}
-
-	line, err := internal.DisplayStream(buffer)
+	hostname, err := redeployService(path, body, app, service, operation, printProgress)
 	internal.Check(err)
-	if len(line) > 0 {
-		var data map[string]interface{}
-		err = json.Unmarshal(line, &data)
-		internal.Check(err)
-
-		fmt.Printf("Hostname: %v\n", data["hostname"])
+	if hostname != "" {
+		fmt.Printf("Hostname: %v\n", hostname)
 	}
 
-	if !redeployBatch {
+	if !batch {
 		internal.ExitAfterCtrlC()
 	}
 }
+
+// redeployService posts a redeploy body, passes its progress to report, records
+// the change in the deployment journal and returns the hostname of the service
+func redeployService(path string, body []byte, app, service, operation string, report func(string)) (string, error) {
+	return journaled(operation, app, service, report, func() (string, error) {
+		return postRedeploy(path, body, report)
+	})
+}
+
+// postRedeploy posts a redeploy body, passes its progress to report and
+// returns the hostname of the service. The change is not journaled.
+func postRedeploy(path string, body []byte, report func(string)) (string, error) {
+	line, err := streamRequest("POST", path, body, report)
+	if err != nil {
+		return "", err
+	}
+	return hostname(line)
+}
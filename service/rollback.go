@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+)
+
+var (
+	rollbackTo    int
+	rollbackBatch bool
+)
+
+func rollbackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Redeploy a previous definition of a service: sail service rollback [<applicationName>/]<serviceId> [--to ID]",
+		Long: `Redeploy a previous definition of a service: sail service rollback [<applicationName>/]<serviceId> [--to ID]
+
+Without --to, the last recorded change other than a rollback is reverted: the
+service is redeployed with the definition it had before. Rolling back twice
+thus does not revert the first rollback. With --to, the service is redeployed
+with the definition it had right after the entry ID of 'sail service history'.
+
+The whole definition is redeployed: a field which was not set in the definition
+rolled back to is cleared.
+	"example: sail service rollback my-app/web --to 12"
+`,
+		Run: cmdRollback,
+	}
+
+	cmd.Flags().IntVar(&rollbackTo, "to", 0, "history entry ID to roll back to")
+	cmd.Flags().BoolVar(&rollbackBatch, "batch", false, "do not attach console on redeploy")
+
+	return cmd
+}
+
+func cmdRollback(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail service rollback [<applicationName>/]<serviceId>. Please see sail service rollback --help")
+		os.Exit(1)
+	}
+
+	host, app, service, _, err := internal.ParseResourceName(args[0])
+	internal.Check(err)
+
+	if !internal.CheckHostConsistent(host) {
+		fmt.Fprintf(os.Stderr, "Error: Invalid Host %s for endpoint %s\n", host, internal.Host)
+		os.Exit(1)
+	}
+
+	entries, err := ReadJournal(app, service)
+	internal.Check(err)
+	if len(entries) == 0 {
+		internal.Exit("Error: No deployment recorded for %s/%s\n", app, service)
+	}
+
+	var target ServiceSpec
+	var description string
+	if rollbackTo == 0 {
+		var last *JournalEntry
+		for i := len(entries) - 1; i >= 0 && last == nil; i-- {
+			if entries[i].Operation != "rollback" {
+				last = &entries[i]
+			}
+		}
+		if last == nil {
+			internal.Exit("Error: Only rollbacks recorded for %s/%s, use --to to pick a definition\n", app, service)
+		}
+		if last.Previous == nil {
+			internal.Exit("Error: Entry %d created %s/%s, there is no previous definition to roll back to\n", last.ID, app, service)
+		}
+		target = *last.Previous
+		description = fmt.Sprintf("definition before entry %d", last.ID)
+	} else {
+		found := false
+		for _, entry := range entries {
+			if entry.ID == rollbackTo {
+				target, found = entry.Next, true
+			}
+		}
+		if !found {
+			internal.Exit("Error: No entry %d in the history of %s/%s\n", rollbackTo, app, service)
+		}
+		description = fmt.Sprintf("definition of entry %d", rollbackTo)
+	}
+
+	fmt.Fprintf(os.Stderr, "Rolling back %s/%s to the %s (%s:%s)\n", app, service, description, target.Repository, target.RepositoryTag)
+
+	doServiceRedeploy(target.FullRedeploy(), app, service, "rollback", rollbackBatch)
+}
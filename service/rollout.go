@@ -0,0 +1,141 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/runabove/sail/internal"
+)
+
+// Exit statuses of an aborted redeploy followed with --wait
+const (
+	exitRolloutTimeout   = 3
+	exitRolloutCrashLoop = 4
+)
+
+// crashLoopThreshold is the number of exits of a single container after which
+// a rollout is considered crash-looping
+const crashLoopThreshold = 3
+
+// rolloutError aborts a rollout with an exit status
+type rolloutError struct {
+	code    int
+	message string
+}
+
+func (e *rolloutError) Error() string {
+	return e.message
+}
+
+// rollout is a redeploy of a service followed until its containers run
+type rollout struct {
+	app      string
+	service  string
+	previous ServiceSpec
+	exits    map[string]int // number of exits of each container
+}
+
+// waitRedeploy redeploys a service then waits until all its containers are
+// running. The API replaces all the containers at once: the rollout can only be
+// followed, and aborted as soon as it goes wrong.
+func waitRedeploy(args Redeploy, app, service string) {
+	previous, err := fetchServiceSpec(app, service)
+	internal.Check(err)
+
+	r := &rollout{
+		app:      app,
+		service:  service,
+		previous: previous,
+		exits:    make(map[string]int),
+	}
+
+	number := args.ContainerNumber
+	if number == 0 {
+		number = previous.ContainerNumber
+	}
+
+	hostname := r.step(func() (string, error) {
+		body, err := json.Marshal(args)
+		if err != nil {
+			return "", err
+		}
+		return postRedeploy(fmt.Sprintf("/applications/%s/services/%s/redeploy", app, service), body, printProgress)
+	})
+	r.wait(number)
+
+	r.record()
+	fmt.Fprintf(os.Stderr, "Rollout of %s/%s succeeded\n", app, service)
+	if hostname != "" {
+		fmt.Printf("Hostname: %v\n", hostname)
+	}
+}
+
+// step runs a request of the rollout, returning the hostname of the service,
+// and aborts the rollout if the request fails. The request stream is closed
+// when step returns, so that a rollback never starts while it is still running.
+func (r *rollout) step(request func() (string, error)) string {
+	hostname, err := request()
+	if err != nil {
+		r.abort(1, "%s", err)
+	}
+	return hostname
+}
+
+// wait waits until the service runs number containers, or aborts the rollout
+func (r *rollout) wait(number int) {
+	err := WaitFor(r.app, r.service, redeployTimeout, r.event, func() (bool, error) {
+		status, err := fetchServiceStatus(r.app, r.service)
+		if err != nil {
+			return false, err
+		}
+		return len(status.Containers) >= number && status.reached("running"), nil
+	})
+
+	if err == nil {
+		return
+	} else if e, ok := err.(*rolloutError); ok {
+		r.abort(e.code, "%s", e.message)
+	} else if err == ErrWaitTimeout {
+		r.abort(exitRolloutTimeout, "%d containers are not running after %s", number, redeployTimeout)
+	}
+	r.abort(1, "%s", err)
+}
+
+// event prints an event of the service and counts the exits of its containers
+func (r *rollout) event(ev *internal.Event) error {
+	fmt.Fprintln(os.Stderr, ev.Message)
+
+	if ev.Data != nil && ev.Data.LastExitStatus != nil {
+		r.exits[ev.ID]++
+		if r.exits[ev.ID] >= crashLoopThreshold {
+			return &rolloutError{exitRolloutCrashLoop, fmt.Sprintf("container %s exited %d times", ev.ID, r.exits[ev.ID])}
+		}
+	}
+	return nil
+}
+
+// record records the changes of the rollout in the deployment journal
+func (r *rollout) record() {
+	if err := journalRecord("redeploy", &r.previous, r.app, r.service); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record deployment history: %s\n", err)
+	}
+}
+
+// abort records the changes of the rollout, redeploys the previous definition
+// with --rollback, then exits with code
+func (r *rollout) abort(code int, format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Error: rollout of %s/%s aborted: %s\n", r.app, r.service, fmt.Sprintf(format, a...))
+	r.record()
+
+	if redeployRollback {
+		fmt.Fprintf(os.Stderr, "Rolling back %s/%s to %s:%s...\n", r.app, r.service, r.previous.Repository, r.previous.RepositoryTag)
+		body, err := json.Marshal(r.previous.FullRedeploy())
+		internal.Check(err)
+		path := fmt.Sprintf("/applications/%s/services/%s/redeploy", r.app, r.service)
+		if _, err := redeployService(path, body, r.app, r.service, "rollback", printProgress); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: rollback of %s/%s failed: %s\n", r.app, r.service, err)
+		}
+	}
+	os.Exit(code)
+}
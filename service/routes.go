@@ -0,0 +1,76 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/runabove/sail/internal"
+)
+
+// Route is an HTTP route attached to a service
+type Route struct {
+	Domain  string `json:"domain,omitempty"`
+	Pattern string `json:"pattern"`
+	Method  string `json:"method"`
+}
+
+// ListRoutes returns the routes attached to a service
+func ListRoutes(app, service string) []Route {
+	routes, err := listRoutes(app, service)
+	internal.Check(err)
+	return routes
+}
+
+// listRoutes returns the routes attached to a service, or an error instead of
+// exiting
+func listRoutes(app, service string) ([]Route, error) {
+	var routes []Route
+	b, err := fetch(fmt.Sprintf("/applications/%s/services/%s/attached-routes", app, service))
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &routes)
+	return routes, err
+}
+
+// AttachRoute attaches an HTTP route of domain to a service
+func AttachRoute(app, service, domain, pattern, method string) error {
+	path := fmt.Sprintf("/applications/%s/services/%s/attached-routes/%s", app, service, domain)
+	if err := routeRequest("POST", http.StatusCreated, path, pattern, method); err != nil {
+		return fmt.Errorf("could not attach route %s %s%s to service %s/%s: %s", method, domain, pattern, app, service, err)
+	}
+	fmt.Fprintf(os.Stderr, "Attached route %s %s%s to service %s/%s\n", method, domain, pattern, app, service)
+	return nil
+}
+
+// DetachRoute detaches an HTTP route of domain from a service
+func DetachRoute(app, service, domain, pattern, method string) error {
+	path := fmt.Sprintf("/applications/%s/services/%s/attached-routes/%s", app, service, domain)
+	if err := routeRequest("DELETE", http.StatusOK, path, pattern, method); err != nil {
+		return fmt.Errorf("could not detach route %s %s%s from service %s/%s: %s", method, domain, pattern, app, service, err)
+	}
+	fmt.Fprintf(os.Stderr, "Detached route %s %s%s from service %s/%s\n", method, domain, pattern, app, service)
+	return nil
+}
+
+// routeRequest sends a route to path and returns an error instead of exiting
+// when the answer is not wantCode
+func routeRequest(method string, wantCode int, path, pattern, routeMethod string) error {
+	body, err := json.Marshal(Route{Pattern: pattern, Method: routeMethod})
+	if err != nil {
+		return err
+	}
+
+	b, code, err := internal.Request(method, path, body)
+	if err != nil {
+		return err
+	} else if code != wantCode {
+		if e := internal.DecodeError(b); e != nil {
+			return e
+		}
+		return fmt.Errorf("unexpected status code %d", code)
+	}
+	return nil
+}
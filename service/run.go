@@ -0,0 +1,150 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+)
+
+var (
+	runSpec specFlags
+	runName string
+	runKeep bool
+)
+
+// RunCmd runs a one-off command in a temporary service
+var RunCmd = runCmd()
+
+func runCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run a one-off command in a temporary service: sail run [<applicationName>/]<repository>[:tag] [--name <serviceId>] [-- <command> [args...]]",
+		Long: `Run a one-off command in a temporary service: sail run [<applicationName>/]<repository>[:tag] [--name <serviceId>] [-- <command> [args...]]
+
+A service with a single container and restart policy 'no' is created with the
+given command, its console is attached and sail exits with the exit status of
+the container (255 if it was killed by a signal). The service is then deleted,
+unless --keep is set. When interrupted, sail offers to delete it.
+
+Other flags are those of 'sail service add'. A command given after -- takes
+precedence on --command.
+	"example: sail run my-app/web:v2 --name migrate-123 -- ./manage.py migrate"
+`,
+		Run: cmdRun,
+	}
+
+	runSpec.bind(cmd, true)
+	cmd.Flags().StringVar(&runName, "name", "", "name of the temporary service, default to run-<timestamp>")
+	cmd.Flags().BoolVar(&runKeep, "keep", false, "do not delete the service once the command exited")
+
+	return cmd
+}
+
+func cmdRun(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail run [<applicationName>/]<repository>[:tag] [-- <command> [args...]]. Please see sail run --help")
+		os.Exit(1)
+	}
+
+	host, app, repository, tag, err := internal.ParseResourceName(args[0])
+	internal.Check(err)
+
+	if !internal.CheckHostConsistent(host) {
+		fmt.Fprintf(os.Stderr, "Error: Invalid Host %s for endpoint %s\n", host, internal.Host)
+		os.Exit(1)
+	}
+
+	if runName == "" {
+		runName = fmt.Sprintf("run-%d", time.Now().Unix())
+	}
+
+	// The exit status is the one of a single container, which is not restarted
+	if runSpec.number != 1 || runSpec.restart != "no" {
+		internal.Exit("Error: sail run only supports --number 1 and --restart no\n")
+	}
+
+	spec := ServiceSpec{
+		Application:   app,
+		Service:       runName,
+		Repository:    repository,
+		RepositoryTag: tag,
+	}
+	internal.Check(runSpec.parse(&spec))
+	if len(args) > 1 {
+		spec.ContainerCommand = args[1:]
+	}
+
+	if created, _ := doServiceAdd(spec, false); !created {
+		os.Exit(1)
+	}
+
+	os.Exit(runService(app, runName))
+}
+
+// runService starts a service, attaches its console until its container exits
+// and cleans it up. Returns the exit status of the container.
+func runService(app, service string) int {
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+
+	// Follow events before starting, not to miss a quick exit
+	events := make(chan *internal.Event)
+	done := make(chan struct{})
+	go followEvents(app, service, events, done)
+
+	internal.StreamPrint("GET", fmt.Sprintf("/applications/%s/services/%s/attach", app, service), nil)
+	doServiceStart(app, service)
+
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	status := -1
+	for status < 0 {
+		select {
+		case ev := <-events:
+			if ev.Data != nil && ev.Data.LastExitStatus != nil {
+				if ev.Data.LastExitStatus.ExitStatus != nil {
+					status = *ev.Data.LastExitStatus.ExitStatus
+				} else {
+					status = 255
+				}
+			}
+
+		case <-ticker.C:
+			// The event stream may have dropped: the exit status is then unknown
+			current, err := fetchServiceStatus(app, service)
+			if err == nil && current.reached("stopped") {
+				fmt.Fprintf(os.Stderr, "Warning: %s/%s stopped but its exit status is unknown\n", app, service)
+				status = 1
+			}
+
+		case <-interrupted:
+			close(done)
+			signal.Stop(interrupted)
+			fmt.Fprintln(os.Stderr)
+			if internal.Confirm(fmt.Sprintf("Interrupted. Delete service %s/%s?", app, service)) {
+				serviceDelete(app, service)
+			} else {
+				fmt.Fprintf(os.Stderr, "Service %s/%s kept\n", app, service)
+			}
+			return 130
+		}
+	}
+	close(done)
+
+	// Let the console print the last lines
+	time.Sleep(time.Second)
+
+	if runKeep {
+		fmt.Fprintf(os.Stderr, "Service %s/%s kept, see 'sail service logs %s/%s'\n", app, service, app, service)
+	} else {
+		serviceDelete(app, service)
+	}
+
+	return status
+}
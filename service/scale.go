@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -13,12 +15,21 @@ import (
 var scaleBatch bool
 var scaleDestroy bool
 var scaleNumber int
-var scaleUsage = "usage: sail services scale [-h] [--number NUMBER] [--batch] [--destroy] [<application>/]<service>"
-var scaleLongUsage = `usage: sail services scale [-h] [--number NUMBER] [--batch] [--destroy] [<application>/]<service>
+var scaleUsage = "usage: sail services scale [-h] [--number NUMBER] [--batch] [--destroy] [--filter KEY=VALUE] [--yes] [<application>/]<service>[=[+|-]NUMBER]..."
+var scaleLongUsage = `usage: sail services scale [-h] [--number NUMBER] [--batch] [--destroy] [--filter KEY=VALUE] [--yes] [<application>/]<service>[=[+|-]NUMBER]...
 
-The command will exit as soon as all service containers have stopped.
+When a single service is scaled to --number, the command attaches its console,
+unless --batch is set, and exits as soon as all service containers have stopped.
 Its exit status will be the one of the last container. If the last container was stopped with
-a signal, the command exits with an exit status of 255.`
+a signal, the command exits with an exit status of 255.
+
+Otherwise, the services are scaled in parallel, without attaching, each to its own
+number of containers or to --number. A number prefixed with + or - is relative
+to the current number of containers of the service. The command exits with an
+exit status of 1 if any service could not be scaled.
+
+` + selectorUsage + `
+	"example: sail service scale my-app/web=+2 my-app/worker=4 my-app/cron=-1"`
 
 // Scale json data arguments
 type Scale struct {
@@ -31,33 +42,160 @@ func scaleCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "scale",
 		Short: scaleUsage,
-		Long:  scaleUsage,
+		Long:  scaleLongUsage,
 		Run:   cmdScale,
 	}
 
 	cmd.Flags().BoolVar(&scaleBatch, "batch", false, "do not attach console on start")
 	cmd.Flags().BoolVar(&scaleDestroy, "destroy", false, "when scaling down, prune last stopped containers")
-	cmd.Flags().IntVar(&scaleNumber, "number", 0, "scale to `number` of containers")
+	cmd.Flags().IntVar(&scaleNumber, "number", 0, "scale to `number` of containers, required unless given per service")
+	bulk.bind(cmd)
 
 	return cmd
 }
 
 func cmdScale(cmd *cobra.Command, args []string) {
-	if len(args) != 1 {
+	if len(args) < 1 {
 		fmt.Fprintln(os.Stderr, scaleUsage)
 		os.Exit(1)
 	}
 
-	// Split namespace and service
-	host, app, service, _, err := internal.ParseResourceName(args[0])
-	internal.Check(err)
+	numberSet := cmd.Flags().Changed("number")
+	if numberSet && scaleNumber < 0 {
+		internal.Exit("Error: --number must not be negative\n")
+	}
+
+	var names []string
+	var targets []scaleTarget
+	for _, arg := range args {
+		name, target, err := parseScaleTarget(arg)
+		internal.Check(err)
+		names = append(names, name)
+		bulk.checkStdin([]string{name})
+
+		if !target.given {
+			if !numberSet {
+				internal.Exit("Error: no number of containers for %s. Please set --number or use %s=NUMBER\n", name, arg)
+			}
+			target.value = scaleNumber
+		}
+
+		services, err := bulk.resolve([]string{name})
+		internal.Check(err)
+		for _, service := range services {
+			target.selected = service
+			targets, err = addScaleTarget(targets, target)
+			internal.Check(err)
+		}
+	}
+
+	if len(targets) == 0 {
+		fmt.Fprintln(os.Stderr, "No service selected")
+		return
+	}
+
+	// Historical behaviour: attach and exit with the status of the containers
+	if len(targets) == 1 && !targets[0].relative && !bulk.selecting(names) {
+		serviceScale(targets[0].app, targets[0].service, targets[0].value, scaleDestroy, scaleBatch)
+		return
+	}
+
+	services := make([]selected, len(targets))
+	for i, target := range targets {
+		services[i] = target.selected
+	}
+	if !bulk.confirm(names, "Scale", services) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(1)
+	}
 
-	if !internal.CheckHostConsistent(host) {
-		fmt.Fprintf(os.Stderr, "Error: Invalid Host %s for endpoint %s\n", host, internal.Host)
+	if !scaleServices(targets, scaleDestroy) {
 		os.Exit(1)
 	}
+}
+
+// scaleTarget is a service to scale and its wanted number of containers
+type scaleTarget struct {
+	selected
+	given    bool // value was given with the service
+	relative bool
+	value    int
+}
+
+// parseScaleTarget parses <selector>[=[+|-]NUMBER] and returns the selector
+func parseScaleTarget(arg string) (string, scaleTarget, error) {
+	var target scaleTarget
+
+	name := arg
+	if i := strings.LastIndex(arg, "="); i >= 0 {
+		name = arg[:i]
+		value := arg[i+1:]
+
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return name, target, fmt.Errorf("Invalid number of containers '%s' for %s", value, name)
+		}
+		target.given = true
+		target.relative = strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-")
+		target.value = number
+
+		if !target.relative && number < 0 {
+			return name, target, fmt.Errorf("Invalid number of containers '%s' for %s", value, name)
+		}
+	}
+
+	return name, target, nil
+}
 
-	serviceScale(app, service, scaleNumber, scaleDestroy, scaleBatch)
+// addScaleTarget appends target to targets, unless its service already has the
+// same target. A service selected twice with different targets is an error.
+func addScaleTarget(targets []scaleTarget, target scaleTarget) ([]scaleTarget, error) {
+	for _, other := range targets {
+		if other.selected != target.selected {
+			continue
+		}
+		if other.relative != target.relative || other.value != target.value {
+			return nil, fmt.Errorf("Conflicting numbers of containers for %s", target.selected)
+		}
+		return targets, nil
+	}
+	return append(targets, target), nil
+}
+
+// scaleServices scales services in parallel. Returns false if any failed.
+func scaleServices(targets []scaleTarget, destroy bool) bool {
+	services := make([]selected, len(targets))
+	byService := make(map[selected]scaleTarget)
+	for i, target := range targets {
+		services[i] = target.selected
+		byService[target.selected] = target
+	}
+
+	return runBulk(services, bulk.concurrency, func(service selected, report func(string)) (string, error) {
+		target := byService[service]
+
+		status, err := fetchServiceStatus(service.app, service.service)
+		if err != nil {
+			return "", err
+		}
+
+		from, to := status.ContainerNumber, target.value
+		if target.relative {
+			to += from
+		}
+		details := fmt.Sprintf("%d -> %d containers", from, to)
+
+		if to < 0 {
+			return details, fmt.Errorf("can not scale to %d containers", to)
+		}
+		if to == from {
+			return fmt.Sprintf("unchanged, %d containers", to), nil
+		}
+
+		report(fmt.Sprintf("scaling from %d to %d containers", from, to))
+		_, err = scaleService(service.app, service.service, to, destroy, report)
+		return details, err
+	})
 }
 
 // serviceScale start service (without attach)
@@ -69,6 +207,46 @@ func serviceScale(app string, service string, number int, destroy bool, batch bo
 	// stream service events in a goroutine
 	internal.EventStreamPrint("GET", fmt.Sprintf("/applications/%s/services/%s/events", app, service), nil, true)
 
+	doServiceScale(app, service, number, destroy)
+
+	if !batch {
+		internal.ExitAfterCtrlC()
+	}
+}
+
+// doServiceScale issues the scale request, displays its progress and records
+// the change in the deployment journal
+func doServiceScale(app string, service string, number int, destroy bool) {
+	hostname, err := scaleService(app, service, number, destroy, printProgress)
+	internal.Check(err)
+	if hostname != "" {
+		fmt.Printf("Hostname: %v\n", hostname)
+	}
+}
+
+// ScaleService scales a service without attaching its console. Unlike the scale
+// command, errors are returned so that long running callers can retry. The
+// change is not recorded in the deployment journal, so that automatic scaling
+// does not push deployments out of it.
+func ScaleService(app string, service string, number int) error {
+	hostname, err := postScale(app, service, number, false, printProgress)
+	if hostname != "" {
+		fmt.Printf("Hostname: %v\n", hostname)
+	}
+	return err
+}
+
+// scaleService issues the scale request, passes its progress to report, records
+// the change in the deployment journal and returns the hostname of the service
+func scaleService(app string, service string, number int, destroy bool, report func(string)) (string, error) {
+	return journaled("scale", app, service, report, func() (string, error) {
+		return postScale(app, service, number, destroy, report)
+	})
+}
+
+// postScale issues the scale request, passes its progress to report and returns
+// the hostname of the service. The change is not journaled.
+func postScale(app string, service string, number int, destroy bool, report func(string)) (string, error) {
 	path := fmt.Sprintf("/applications/%s/services/%s/scale", app, service)
 
 	args := Scale{
@@ -77,22 +255,19 @@ func serviceScale(app string, service string, number int, destroy bool, batch bo
 	}
 
 	data, err := json.Marshal(&args)
-	internal.Check(err)
-
-	buffer, _, err := internal.Stream("POST", path, data)
-	internal.Check(err)
-
-	line, err := internal.DisplayStream(buffer)
-	internal.Check(err)
-	if len(line) > 0 {
-		var data map[string]interface{}
-		err = json.Unmarshal(line, &data)
-		internal.Check(err)
-
-		fmt.Printf("Hostname: %v\n", data["hostname"])
+	if err != nil {
+		return "", err
 	}
 
-	if !batch {
-		internal.ExitAfterCtrlC()
+	line, err := streamRequest("POST", path, data, report)
+	if err != nil {
+		return "", err
 	}
+	return hostname(line)
+}
+
+// ContainerNumber returns the number of containers a service is scaled to
+func ContainerNumber(app string, service string) (int, error) {
+	status, err := fetchServiceStatus(app, service)
+	return status.ContainerNumber, err
 }
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+)
+
+// selectorUsage documents selectors in the help of the commands using them
+const selectorUsage = `Several services may be given. They may also be selected with glob patterns,
+such as 'my-app/review-*', with --filter repository=<pattern> or --filter
+state=<state>, and read from stdin with '-'. Selected services are listed and
+confirmation is asked, unless --yes is set; --yes is required when stdin is not
+a terminal. They are handled --concurrency at a time, without attaching, then a
+summary is printed. The exit status is 1 if any of them failed.
+`
+
+// bulk holds the selection flags of the commands acting on several services
+var bulk selector
+
+// selector selects services with glob patterns on [<applicationName>/]<serviceId>,
+// filters on their definition and identifiers read from stdin ('-')
+type selector struct {
+	filters     []string
+	yes         bool
+	concurrency int
+
+	// impact, when set, describes what an action on a service affects. Named
+	// services are then confirmed too, when stdin is a terminal.
+	impact func(selected) []string
+}
+
+// selected is a service picked by a selector
+type selected struct {
+	app     string
+	service string
+}
+
+func (s selected) String() string {
+	return fmt.Sprintf("%s/%s", s.app, s.service)
+}
+
+func (s *selector) bind(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&s.filters, "filter", nil, "select services by 'repository=<pattern>' or 'state=<state>'")
+	cmd.Flags().BoolVar(&s.yes, "yes", false, "do not ask for confirmation of a selection")
+	cmd.Flags().IntVar(&s.concurrency, "concurrency", 4, "maximum number of services handled at the same time")
+}
+
+// active tells whether args are selectors rather than a single service
+func (s *selector) active(args []string) bool {
+	return len(args) > 1 || s.selecting(args)
+}
+
+// selecting tells whether args or filters select services, as opposed to naming them
+func (s *selector) selecting(args []string) bool {
+	if len(s.filters) > 0 {
+		return true
+	}
+	for _, arg := range args {
+		if arg == "-" || isPattern(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPattern(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// resolve returns the services matching patterns and filters, in order and
+// without duplicates
+func (s *selector) resolve(patterns []string) ([]selected, error) {
+	filters, err := parseFilters(s.filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, pattern := range patterns {
+		if pattern != "-" {
+			names = append(names, pattern)
+			continue
+		}
+
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				names = append(names, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var result []selected
+	seen := make(map[selected]bool)
+	for _, name := range names {
+		matches, err := s.expand(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
+
+			ok, err := match.matches(filters)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				result = append(result, match)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// expand returns the services matching a [<applicationName>/]<serviceId> glob pattern
+func (s *selector) expand(pattern string) ([]selected, error) {
+	host, app, service, _, err := internal.ParseResourceName(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if !internal.CheckHostConsistent(host) {
+		return nil, fmt.Errorf("Invalid Host %s for endpoint %s", host, internal.Host)
+	}
+
+	apps := []string{app}
+	if isPattern(app) {
+		apps = nil
+		for _, candidate := range internal.GetListApplications(nil) {
+			if ok, err := path.Match(app, candidate); err != nil {
+				return nil, fmt.Errorf("Invalid pattern '%s': %s", pattern, err)
+			} else if ok {
+				apps = append(apps, candidate)
+			}
+		}
+	}
+
+	var matches []selected
+	for _, app := range apps {
+		if !isPattern(service) {
+			matches = append(matches, selected{app, service})
+			continue
+		}
+		for _, candidate := range ListServices(app) {
+			if ok, err := path.Match(service, candidate); err != nil {
+				return nil, fmt.Errorf("Invalid pattern '%s': %s", pattern, err)
+			} else if ok {
+				matches = append(matches, selected{app, candidate})
+			}
+		}
+	}
+	return matches, nil
+}
+
+var filterKeys = []string{"repository", "state"}
+
+func parseFilters(filters []string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	for _, filter := range filters {
+		kv := strings.SplitN(filter, "=", 2)
+		if len(kv) != 2 || !internal.StringIn(kv[0], filterKeys) {
+			return nil, fmt.Errorf("Invalid filter '%s'. Must be one of 'repository=<pattern>' and 'state=<state>'", filter)
+		}
+		parsed[kv[0]] = kv[1]
+	}
+	return parsed, nil
+}
+
+// matches tells whether the service matches all filters
+func (s selected) matches(filters map[string]string) (bool, error) {
+	if len(filters) == 0 {
+		return true, nil
+	}
+
+	var definition struct {
+		Repository string `json:"repository"`
+		State      string `json:"state"`
+	}
+	b, err := fetch(fmt.Sprintf("/applications/%s/services/%s", s.app, s.service))
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", s, err)
+	}
+	if err := json.Unmarshal(b, &definition); err != nil {
+		return false, err
+	}
+
+	if pattern, ok := filters["repository"]; ok {
+		if ok, err := path.Match(pattern, definition.Repository); err != nil || !ok {
+			return false, err
+		}
+	}
+	if state, ok := filters["state"]; ok && !strings.EqualFold(state, definition.State) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// confirm lists the selected services and asks for confirmation, unless --yes
+// is set or the services were named explicitly. A selection can not be
+// confirmed without --yes when stdin is not a terminal.
+func (s *selector) confirm(args []string, action string, services []selected) bool {
+	question := fmt.Sprintf("%s %d service(s)?", action, len(services))
+
+	if !s.selecting(args) {
+		if s.impact == nil {
+			return true
+		}
+		return internal.ConfirmImpact(s.yes, question, func() []string {
+			var lines []string
+			for _, service := range services {
+				for _, line := range s.impact(service) {
+					lines = append(lines, fmt.Sprintf("%s: %s", service, line))
+				}
+			}
+			return lines
+		})
+	}
+
+	fmt.Fprintf(os.Stderr, "%d service(s) selected:\n", len(services))
+	for _, service := range services {
+		fmt.Fprintf(os.Stderr, "  %s\n", service)
+		if s.impact != nil && !s.yes {
+			for _, line := range s.impact(service) {
+				fmt.Fprintf(os.Stderr, "    - %s\n", line)
+			}
+		}
+	}
+	if s.yes {
+		return true
+	}
+	if !internal.IsTerminal() {
+		internal.Exit("Error: --yes is required to confirm a selection when stdin is not a terminal\n")
+	}
+	return internal.Confirm(question)
+}
+
+// run resolves args, asks for confirmation and calls fn on each selected
+// service. Exits with status 1 if fn failed on any of them.
+func (s *selector) run(args []string, action string, fn func(service selected, report func(string)) (string, error)) {
+	s.checkStdin(args)
+	services, err := s.resolve(args)
+	internal.Check(err)
+
+	if len(services) == 0 {
+		fmt.Fprintln(os.Stderr, "No service selected")
+		return
+	}
+	if !s.confirm(args, action, services) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		os.Exit(1)
+	}
+
+	if !runBulk(services, s.concurrency, fn) {
+		os.Exit(1)
+	}
+}
+
+// checkStdin exits if services are to be read from stdin, which is then not
+// available to answer the confirmation
+func (s *selector) checkStdin(args []string) {
+	if internal.StringIn("-", args) && !s.yes {
+		internal.Exit("Error: --yes is required when reading services from stdin\n")
+	}
+}
+
+// runBulk calls fn on services, at most concurrency at a time, prints their
+// progress prefixed with their name and a summary. The string returned by fn
+// details its outcome. Returns false if fn failed on any service.
+func runBulk(services []selected, concurrency int, fn func(service selected, report func(string)) (string, error)) bool {
+	type result struct {
+		details string
+		err     error
+	}
+	results := make([]result, len(services))
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for i, service := range services {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, service selected) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			report := func(message string) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				fmt.Fprintf(os.Stderr, "%s: %s\n", service, message)
+			}
+
+			details, err := fn(service, report)
+			if err != nil {
+				report(fmt.Sprintf("Error: %s", err))
+			}
+			results[i] = result{details, err}
+		}(i, service)
+	}
+	wg.Wait()
+
+	ok := true
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	titles := []string{"SERVICE", "STATUS", "DETAILS"}
+	fmt.Fprintln(w, strings.Join(titles, "\t"))
+
+	for i, service := range services {
+		status, details := "OK", results[i].details
+		if results[i].err != nil {
+			status, details = "FAILED", results[i].err.Error()
+			ok = false
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", service, status, details)
+	}
+	w.Flush()
+
+	return ok
+}
+
+// streamRequest issues a request answered with a progress stream, passes the
+// progress to report and returns the last line
+func streamRequest(method, path string, body []byte, report func(string)) ([]byte, error) {
+	buffer, _, err := internal.Stream(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return internal.ReadStream(buffer, report, func(line []byte) {
+		report(strings.TrimSpace(string(line)))
+	})
+}
+
+// hostname returns the hostname found in the last line of a stream, if any
+func hostname(line []byte) (string, error) {
+	if len(line) == 0 {
+		return "", nil
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(line, &data); err != nil {
+		return "", err
+	}
+	if data["hostname"] == nil {
+		return "", nil
+	}
+	return fmt.Sprint(data["hostname"]), nil
+}
+
+// printProgress prints a progress message of a stream on stderr
+func printProgress(message string) {
+	fmt.Fprintln(os.Stderr, message)
+}
@@ -18,6 +18,10 @@ func init() {
 	Cmd.AddCommand(startCmd())
 	Cmd.AddCommand(stopCmd())
 	Cmd.AddCommand(scaleCmd())
+	Cmd.AddCommand(copyCmd())
+	Cmd.AddCommand(cmdServiceHistory)
+	Cmd.AddCommand(rollbackCmd())
+	Cmd.AddCommand(waitCmd())
 }
 
 // Cmd service
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/shlex"
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+)
+
+// ServiceSpec holds the complete definition of a service. It is the single
+// model shared by add, redeploy and ensure mode and converts losslessly to
+// both Add and Redeploy request bodies.
+type ServiceSpec struct {
+	Application          string                         `json:"namespace"`
+	Service              string                         `json:"name"`
+	Repository           string                         `json:"repository"`
+	RepositoryTag        string                         `json:"repository_tag"`
+	ContainerModel       string                         `json:"container_model,omitempty"`
+	ContainerNumber      int                            `json:"container_number"`
+	ContainerUser        string                         `json:"container_user,omitempty"`
+	ContainerWorkdir     string                         `json:"container_workdir,omitempty"`
+	ContainerCommand     []string                       `json:"container_command,omitempty"`
+	ContainerEntrypoint  []string                       `json:"container_entrypoint,omitempty"`
+	ContainerEnvironment []string                       `json:"container_environment,omitempty"`
+	ContainerNetwork     map[string]map[string][]string `json:"container_network,omitempty"`
+	ContainerPorts       map[string][]PortConfig        `json:"container_ports,omitempty"`
+	RestartPolicy        string                         `json:"restart_policy,omitempty"`
+	Links                map[string]string              `json:"links,omitempty"`
+	Volumes              map[string]VolumeConfig        `json:"volumes,omitempty"`
+	Pool                 string                         `json:"pool,omitempty"`
+}
+
+// Add returns the body sent to /applications/%s/services/%s
+func (s ServiceSpec) Add() Add {
+	a := Add{
+		Service:              s.Service,
+		Volumes:              s.Volumes,
+		Repository:           s.Repository,
+		ContainerUser:        s.ContainerUser,
+		RestartPolicy:        s.RestartPolicy,
+		ContainerCommand:     s.ContainerCommand,
+		ContainerNetwork:     s.ContainerNetwork,
+		ContainerEntrypoint:  s.ContainerEntrypoint,
+		ContainerNumber:      s.ContainerNumber,
+		RepositoryTag:        s.RepositoryTag,
+		Links:                s.Links,
+		Application:          s.Application,
+		ContainerWorkdir:     s.ContainerWorkdir,
+		ContainerEnvironment: s.ContainerEnvironment,
+		ContainerModel:       s.ContainerModel,
+		ContainerPorts:       s.ContainerPorts,
+		Pool:                 s.Pool,
+	}
+
+	// These fields are not optional in the add body
+	if a.ContainerNetwork == nil {
+		a.ContainerNetwork = make(map[string]map[string][]string)
+	}
+	if a.Links == nil {
+		a.Links = make(map[string]string)
+	}
+	if a.ContainerPorts == nil {
+		a.ContainerPorts = make(map[string][]PortConfig)
+	}
+	if a.ContainerEnvironment == nil {
+		a.ContainerEnvironment = make([]string, 0)
+	}
+
+	return a
+}
+
+// Redeploy returns the body sent to /applications/%s/services/%s/redeploy
+func (s ServiceSpec) Redeploy() Redeploy {
+	return Redeploy{
+		Service:              s.Service,
+		Volumes:              s.Volumes,
+		Repository:           s.Repository,
+		ContainerUser:        s.ContainerUser,
+		RestartPolicy:        s.RestartPolicy,
+		ContainerCommand:     s.ContainerCommand,
+		ContainerNetwork:     s.ContainerNetwork,
+		ContainerEntrypoint:  s.ContainerEntrypoint,
+		ContainerNumber:      s.ContainerNumber,
+		RepositoryTag:        s.RepositoryTag,
+		Links:                s.Links,
+		Application:          s.Application,
+		ContainerWorkdir:     s.ContainerWorkdir,
+		ContainerEnvironment: s.ContainerEnvironment,
+		ContainerModel:       s.ContainerModel,
+		ContainerPorts:       s.ContainerPorts,
+		Pool:                 s.Pool,
+	}
+}
+
+// FullRedeploy returns the redeploy body of the complete definition. Unlike
+// Redeploy, empty fields are sent, so that fields not set in the definition are
+// cleared on the service instead of being left unchanged.
+func (s ServiceSpec) FullRedeploy() FullRedeploy {
+	r := FullRedeploy{
+		Volumes:              s.Volumes,
+		Repository:           s.Repository,
+		ContainerUser:        s.ContainerUser,
+		RestartPolicy:        s.RestartPolicy,
+		ContainerCommand:     s.ContainerCommand,
+		ContainerNetwork:     s.ContainerNetwork,
+		ContainerEntrypoint:  s.ContainerEntrypoint,
+		ContainerNumber:      s.ContainerNumber,
+		RepositoryTag:        s.RepositoryTag,
+		Links:                s.Links,
+		Application:          s.Application,
+		ContainerWorkdir:     s.ContainerWorkdir,
+		ContainerEnvironment: s.ContainerEnvironment,
+		ContainerModel:       s.ContainerModel,
+		ContainerPorts:       s.ContainerPorts,
+		Pool:                 s.Pool,
+	}
+
+	// Empty collections are sent as such, not as null
+	if r.Volumes == nil {
+		r.Volumes = make(map[string]VolumeConfig)
+	}
+	if r.ContainerCommand == nil {
+		r.ContainerCommand = make([]string, 0)
+	}
+	if r.ContainerNetwork == nil {
+		r.ContainerNetwork = make(map[string]map[string][]string)
+	}
+	if r.ContainerEntrypoint == nil {
+		r.ContainerEntrypoint = make([]string, 0)
+	}
+	if r.Links == nil {
+		r.Links = make(map[string]string)
+	}
+	if r.ContainerEnvironment == nil {
+		r.ContainerEnvironment = make([]string, 0)
+	}
+	if r.ContainerPorts == nil {
+		r.ContainerPorts = make(map[string][]PortConfig)
+	}
+
+	return r
+}
+
+// specFlags holds the raw command line flags describing a service
+type specFlags struct {
+	model        string
+	number       int
+	restart      string
+	tag          string
+	user         string
+	workdir      string
+	command      string
+	entrypoint   string
+	pool         string
+	env          []string
+	envFiles     internal.EnvFilesValue
+	links        []string
+	networks     []string
+	networkAllow []string
+	publish      []string
+	gateways     []string
+	volumes      []string
+}
+
+// bind registers service definition flags on cmd. When add is false, flags
+// default to empty values so that only explicitly given values are redeployed.
+func (f *specFlags) bind(cmd *cobra.Command, add bool) {
+	model, number, restart := "", 0, ""
+	if add {
+		model, number, restart = "x1", 1, "no"
+	}
+
+	cmd.Flags().StringVarP(&f.model, "model", "", model, "Container model")
+	cmd.Flags().IntVarP(&f.number, "number", "", number, "Number of container to run")
+	cmd.Flags().StringSliceVarP(&f.links, "link", "", nil, "name:alias")
+	cmd.Flags().StringSliceVar(&f.networks, "network", nil, "public|private|<namespace name>")
+	cmd.Flags().StringSliceVar(&f.networkAllow, "network-allow", nil, "ip[/mask][:port[-port][/protocol]] Use IPs whitelist, IPv6 followed by ports in brackets: [ip[/mask]]:port")
+	cmd.Flags().StringSliceVarP(&f.publish, "publish", "p", nil, "Publish a container's port to the host: [network:][publishedPort:]containerPort[/protocol], ports may be ranges")
+	cmd.Flags().StringSliceVar(&f.gateways, "gateway", nil, "network-input:network-output")
+	cmd.Flags().StringVarP(&f.restart, "restart", "", restart, "{no|always[:<max>]|on-failure[:<max>]}")
+	cmd.Flags().StringVarP(&f.command, "command", "", "", "override docker run command")
+	cmd.Flags().StringVarP(&f.tag, "tag", "", "", "deploy from new image version")
+	cmd.Flags().StringVarP(&f.workdir, "workdir", "", "", "override docker workdir")
+	cmd.Flags().StringVarP(&f.entrypoint, "entrypoint", "", "", "override docker entrypoint")
+	cmd.Flags().StringVarP(&f.user, "user", "", "", "override docker user")
+	cmd.Flags().StringSliceVar(&f.volumes, "volume", nil, "/path:size] (Size in GB)")
+	cmd.Flags().StringSliceVarP(&f.env, "env", "e", nil, "override docker environment. Syntax: key=val,...")
+	cmd.Flags().Var(&f.envFiles, "env-file", "read docker environment from a .env file. --env takes precedence")
+	cmd.Flags().StringVarP(&f.pool, "pool", "", "", "Dedicated host pool")
+}
+
+// parse fills spec from the flags and validates the result. Application,
+// service and repository must already be set. A tag given in the repository
+// name takes precedence on --tag. All problems are reported at once.
+func (f *specFlags) parse(spec *ServiceSpec) error {
+	var errs validationErrors
+
+	spec.ContainerModel = f.model
+	spec.ContainerNumber = f.number
+	spec.RestartPolicy = f.restart
+	spec.ContainerUser = f.user
+	spec.ContainerWorkdir = f.workdir
+	spec.Pool = f.pool
+
+	// Parse environment: files in order, then --env
+	var envs [][]string
+	for _, path := range f.envFiles {
+		env, err := internal.ReadEnvFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("Cannot read env file: %s", err))
+		}
+		envs = append(envs, env)
+	}
+	spec.ContainerEnvironment = internal.MergeEnv(append(envs, f.env)...)
+
+	if spec.RepositoryTag == "" {
+		spec.RepositoryTag = f.tag
+	}
+
+	// Parse command
+	if f.command != "" {
+		command, err := shlex.Split(f.command)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("Cannot split command %s: %s", f.command, err))
+		}
+		spec.ContainerCommand = command
+	}
+
+	// Parse Entrypoint
+	if f.entrypoint != "" {
+		entrypoint, err := shlex.Split(f.entrypoint)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("Cannot split entrypoint %s: %s", f.entrypoint, err))
+		}
+		spec.ContainerEntrypoint = entrypoint
+	}
+
+	// Parse volumes
+	if len(f.volumes) > 0 {
+		spec.Volumes = make(map[string]VolumeConfig)
+	}
+	for _, vol := range f.volumes {
+		t := strings.Split(vol, ":")
+		if len(t) == 2 {
+			spec.Volumes[t[0]] = VolumeConfig{Size: t[1]}
+		} else if len(t) == 1 {
+			spec.Volumes[t[0]] = VolumeConfig{Size: "10"}
+		} else {
+			errs = append(errs, fmt.Sprintf("Volume parameter '%s' not formated correctly, should be /path[:size]", vol))
+		}
+	}
+
+	// Parse links
+	if len(f.links) > 0 {
+		spec.Links = make(map[string]string)
+	}
+	for _, link := range f.links {
+		t := strings.Split(link, ":")
+		if len(t) == 1 {
+			spec.Links[t[0]] = t[0]
+		} else if len(t) == 2 && t[0] != "" && t[1] != "" {
+			spec.Links[t[0]] = t[1]
+		} else {
+			errs = append(errs, fmt.Sprintf("Invalid link '%s', should be name[:alias]", link))
+		}
+	}
+
+	// Parse ContainerNetworks arguments
+	if len(f.networks) > 0 || len(f.gateways) > 0 {
+		spec.ContainerNetwork = make(map[string]map[string][]string)
+	}
+	for _, network := range f.networks {
+		spec.ContainerNetwork[network] = make(map[string][]string)
+	}
+
+	for _, gat := range f.gateways {
+		fmt.Fprintln(os.Stderr, "WARNING: --gateway parameter is deprecated")
+
+		t := strings.Split(gat, ":")
+		if len(t) != 2 {
+			errs = append(errs, fmt.Sprintf("Invalid gateway parameter '%s', should be \"input:output\". Typically, output will be one of 'predictor', 'public'", gat))
+			continue
+		}
+		for _, network := range t {
+			if _, ok := spec.ContainerNetwork[network]; !ok {
+				fmt.Fprintf(os.Stderr, "Automatically adding %s to network list\n", network)
+				spec.ContainerNetwork[network] = make(map[string][]string)
+			}
+		}
+		spec.ContainerNetwork[t[0]]["gateway_to"] = append(spec.ContainerNetwork[t[0]]["gateway_to"], t[1])
+	}
+
+	// Parse ContainerPorts
+	ports, err := parsePublishedPort(f.publish)
+	errs.add(err)
+
+	// Parse NetworkAllow
+	spec.ContainerPorts, err = parseWhitelistedCidrs(f.networkAllow, ports)
+	errs.add(err)
+
+	// Semantic checks
+	errs.add(spec.Validate())
+
+	return errs.err()
+}
+
+// ListServices returns the names of the services of an application, sorted
+func ListServices(app string) []string {
+	var services []string
+	b := internal.GetWantJSON(fmt.Sprintf("/applications/%s/services", app))
+	internal.Check(json.Unmarshal(b, &services))
+	sort.Strings(services)
+	return services
+}
+
+// FetchServiceSpec returns the definition of an existing service
+func FetchServiceSpec(app, service string) ServiceSpec {
+	spec, err := fetchServiceSpec(app, service)
+	internal.Check(err)
+	return spec
+}
+
+// fetchServiceSpec returns the definition of an existing service, or an error
+// instead of exiting
+func fetchServiceSpec(app, service string) (ServiceSpec, error) {
+	var spec ServiceSpec
+	b, err := fetch(fmt.Sprintf("/applications/%s/services/%s", app, service))
+	if err != nil {
+		return spec, err
+	}
+	if err := json.Unmarshal(b, &spec); err != nil {
+		return spec, err
+	}
+	spec.Application = app
+	spec.Service = service
+	return spec, nil
+}
+
+// RedeploySpec redeploys a service without attaching its console. Only non
+// empty fields of spec are changed.
+func RedeploySpec(spec ServiceSpec) {
+	doServiceRedeploy(spec.Redeploy(), spec.Application, spec.Service, "redeploy", true)
+}
+
+// ReplaceSpec redeploys a service with the whole definition of spec, without
+// attaching its console. Empty fields of spec are emptied on the service.
+func ReplaceSpec(spec ServiceSpec) {
+	doServiceRedeploy(spec.FullRedeploy(), spec.Application, spec.Service, "redeploy", true)
+}
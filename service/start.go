@@ -1,7 +1,6 @@
 package service
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 
@@ -11,12 +10,13 @@ import (
 )
 
 var startBatch bool
-var startUsage = "usage: sail services start [-h] [--batch] [<applicationName>/]<serviceId>."
-var startLongUsage = `usage: sail services start [-h] [--batch] [<applicationName>/]<serviceId>.
+var startUsage = "usage: sail services start [-h] [--batch] [--filter KEY=VALUE] [--yes] [<applicationName>/]<serviceId>..."
+var startLongUsage = `usage: sail services start [-h] [--batch] [--filter KEY=VALUE] [--yes] [<applicationName>/]<serviceId>...
 
 The command will exit as soon as all service containers have stopped.
 Its exit status will be the one of the last container. If the last container was stopped with
-a signal, the command exits with an exit status of 255.`
+a signal, the command exits with an exit status of 255.
+` + selectorUsage
 
 func startCmd() *cobra.Command {
 
@@ -28,17 +28,26 @@ func startCmd() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&startBatch, "batch", false, "do not attach console on start")
+	bulk.bind(cmd)
 
 	return cmd
 }
 
 func cmdStart(cmd *cobra.Command, args []string) {
 
-	if len(args) != 1 {
+	if len(args) < 1 {
 		fmt.Fprintln(os.Stderr, startUsage)
 		os.Exit(1)
 	}
 
+	if bulk.active(args) {
+		bulk.run(args, "Start", func(s selected, report func(string)) (string, error) {
+			_, err := startService(s.app, s.service, report)
+			return "", err
+		})
+		return
+	}
+
 	// Split namespace and service
 	host, app, service, _, err := internal.ParseResourceName(args[0])
 	internal.Check(err)
@@ -60,21 +69,34 @@ func serviceStart(app string, service string, batch bool) {
 	// stream service events in a goroutine
 	internal.EventStreamPrint("GET", fmt.Sprintf("/applications/%s/services/%s/events", app, service), nil, true)
 
-	path := fmt.Sprintf("/applications/%s/services/%s/start", app, service)
-	buffer, _, err := internal.Stream("POST", path, []byte("{}"))
-	internal.Check(err)
+	doServiceStart(app, service)
 
-	line, err := internal.DisplayStream(buffer)
-	internal.Check(err)
-	if len(line) > 0 {
-		var data map[string]interface{}
-		err = json.Unmarshal(line, &data)
-		internal.Check(err)
+	if !batch {
+		internal.ExitAfterCtrlC()
+	}
+}
 
-		fmt.Printf("Hostname: %v\n", data["hostname"])
+// doServiceStart issues the start request and displays its progress, without attaching nor streaming events
+func doServiceStart(app string, service string) {
+	hostname, err := startService(app, service, printProgress)
+	internal.Check(err)
+	if hostname != "" {
+		fmt.Printf("Hostname: %v\n", hostname)
 	}
+}
 
-	if !batch {
-		internal.ExitAfterCtrlC()
+// startService issues the start request, passes its progress to report and
+// returns the hostname of the service
+func startService(app string, service string, report func(string)) (string, error) {
+	path := fmt.Sprintf("/applications/%s/services/%s/start", app, service)
+	line, err := streamRequest("POST", path, []byte("{}"), report)
+	if err != nil {
+		return "", err
 	}
+	return hostname(line)
+}
+
+// StartService starts a service without attaching its console
+func StartService(app, service string) {
+	doServiceStart(app, service)
 }
@@ -9,29 +9,37 @@ import (
 )
 
 var stopBatch bool
-var stopUsage = "usage: sail services stop [-h] [--batch] [<applicationName>/]<serviceId>"
+var stopUsage = "usage: sail services stop [-h] [--batch] [--filter KEY=VALUE] [--yes] [<applicationName>/]<serviceId>..."
 
 func stopCmd() *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:   "stop",
 		Short: stopUsage,
-		Long:  stopUsage,
+		Long:  stopUsage + "\n\n" + selectorUsage,
 		Run:   cmdStop,
 	}
 
 	cmd.Flags().BoolVar(&startBatch, "batch", false, "do not attach console on stop")
+	bulk.bind(cmd)
 
 	return cmd
 }
 
 func cmdStop(cmd *cobra.Command, args []string) {
 
-	if len(args) != 1 {
+	if len(args) < 1 {
 		fmt.Fprintln(os.Stderr, stopUsage)
 		os.Exit(1)
 	}
 
+	if bulk.active(args) {
+		bulk.run(args, "Stop", func(s selected, report func(string)) (string, error) {
+			return "", stopService(s.app, s.service, report)
+		})
+		return
+	}
+
 	// Split namespace and service
 	host, app, service, _, err := internal.ParseResourceName(args[0])
 	internal.Check(err)
@@ -50,13 +58,17 @@ func serviceStop(app string, service string, batch bool) {
 		internal.StreamPrint("GET", fmt.Sprintf("/applications/%s/services/%s/attach", app, service), nil)
 	}
 
+	internal.Check(stopService(app, service, printProgress))
+}
+
+// stopService issues the stop request and passes its progress to report
+func stopService(app string, service string, report func(string)) error {
 	path := fmt.Sprintf("/applications/%s/services/%s/stop", app, service)
-	buffer, _, err := internal.Stream("POST", path, []byte("{}"))
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(1)
-	}
+	_, err := streamRequest("POST", path, []byte("{}"), report)
+	return err
+}
 
-	_, err = internal.DisplayStream(buffer)
-	internal.Check(err)
+// StopService stops a service without attaching its console
+func StopService(app, service string) {
+	serviceStop(app, service, true)
 }
@@ -0,0 +1,593 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/runabove/sail/internal"
+)
+
+var (
+	topInterval time.Duration
+	topLogLines int
+)
+
+// number of events of the selected service kept on screen
+const topEventLines = 5
+
+// TopCmd is the live dashboard of the services of applications
+var TopCmd = topCmd()
+
+func topCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "top",
+		Aliases: []string{"watch"},
+		Short:   "Live dashboard of the docker services: sail top [applicationName]...",
+		Long: `Live dashboard of the docker services: sail top [applicationName]...
+
+The services of the applications are listed full screen with their state, their
+running containers versus the wanted number and their IPs, and refreshed every
+--interval. The last events and log lines of the selected service are displayed
+below.
+
+Keys:
+  up/down, k/j  select a service
+  s             start the selected service
+  x             stop the selected service
+  r             redeploy the selected service, with its current definition
+  +, -          scale the selected service by one container
+  q, Ctrl-C     quit
+
+Stop and redeploy are only done once confirmed with 'y'. On terminals which can
+not display the dashboard, use 'sail service ls --watch'.
+`,
+		Run: cmdTop,
+	}
+
+	cmd.Flags().DurationVar(&topInterval, "interval", 2*time.Second, "time between two refreshes")
+	cmd.Flags().IntVar(&topLogLines, "lines", 10, "number of log lines of the selected service")
+
+	return cmd
+}
+
+func cmdTop(cmd *cobra.Command, args []string) {
+	if topInterval < time.Second {
+		internal.Exit("Error: --interval must be at least 1s\n")
+	}
+
+	apps := internal.GetListApplications(args)
+	for _, app := range apps {
+		internal.Check(internal.CheckName(app))
+	}
+
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !terminal.IsTerminal(in) || !terminal.IsTerminal(out) {
+		internal.Exit("Error: sail top needs a terminal. Please use sail service ls --watch instead\n")
+	}
+
+	state, err := terminal.MakeRaw(in)
+	internal.Check(err)
+	defer terminal.Restore(in, state)
+
+	// Use the alternate screen, so that the terminal is left as it was
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	newTop(apps).run()
+}
+
+// topService is a service as displayed by the dashboard
+type topService struct {
+	selected
+	State           string `json:"state"`
+	ContainerNumber int    `json:"container_number"`
+	Containers      map[string]struct {
+		State   string `json:"state"`
+		Network map[string]struct {
+			IP string `json:"ip"`
+		} `json:"network"`
+	} `json:"containers"`
+}
+
+// running returns the number of running containers
+func (s topService) running() int {
+	running := 0
+	for _, container := range s.Containers {
+		if strings.EqualFold(container.State, "running") {
+			running++
+		}
+	}
+	return running
+}
+
+// ips returns the IPs of the containers, as network:ip
+func (s topService) ips() []string {
+	ips := []string{}
+	for _, container := range s.Containers {
+		for name, network := range container.Network {
+			ips = append(ips, fmt.Sprintf("%s:%s", name, network.IP))
+		}
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// topRefresh is the result of a refresh of the dashboard
+type topRefresh struct {
+	services []topService
+	logs     []string
+	selected selected
+	err      error
+}
+
+// top is the state of the dashboard. It is only changed by run.
+type top struct {
+	apps     []string
+	services []topService
+	cursor   int
+	current  selected
+	updated  time.Time
+	err      error
+
+	events     []string
+	eventCh    chan *internal.Event
+	eventsDone chan struct{}
+	logs       []string
+
+	status     string
+	pending    string // action waiting for confirmation
+	refreshing bool
+	stale      bool // the selection changed during a refresh
+}
+
+func newTop(apps []string) *top {
+	return &top{apps: apps}
+}
+
+// run refreshes and renders the dashboard and handles keys until the user quits
+func (t *top) run() {
+	keys := make(chan string)
+	go readKeys(keys)
+
+	refreshed := make(chan topRefresh)
+	reports := make(chan string)
+	ticker := time.NewTicker(topInterval)
+	defer ticker.Stop()
+
+	t.refresh(refreshed)
+	t.render()
+	for {
+		select {
+		case key, ok := <-keys:
+			if !ok || !t.key(key, reports) {
+				t.follow(selected{})
+				return
+			}
+			if t.stale {
+				t.refresh(refreshed)
+			}
+
+		case result := <-refreshed:
+			t.refreshing = false
+			t.apply(result)
+			if t.stale {
+				t.refresh(refreshed)
+			}
+
+		case ev := <-t.eventCh:
+			t.events = append(t.events, eventTime(ev).Format("15:04:05")+" "+DescribeEvent(ev))
+			if len(t.events) > topEventLines {
+				t.events = t.events[len(t.events)-topEventLines:]
+			}
+
+		case report := <-reports:
+			t.status = report
+
+		case <-ticker.C:
+			t.refresh(refreshed)
+		}
+		t.render()
+	}
+}
+
+// readKeys sends the keys typed on stdin. Escape sequences are sent whole.
+func readKeys(keys chan<- string) {
+	buffer := make([]byte, 16)
+	for {
+		n, err := os.Stdin.Read(buffer)
+		if err != nil {
+			close(keys)
+			return
+		}
+		keys <- string(buffer[:n])
+	}
+}
+
+// key handles a key. Returns false when the user quits.
+func (t *top) key(key string, reports chan<- string) bool {
+	if t.pending != "" {
+		action := t.pending
+		t.pending = ""
+		if key == "y" || key == "Y" {
+			t.act(action, reports)
+		} else {
+			t.status = fmt.Sprintf("%s %s cancelled", strings.Title(action), t.current)
+		}
+		return true
+	}
+
+	switch key {
+	case "q", "Q", "\x03":
+		return false
+	case "\x1b[A", "k":
+		t.move(-1)
+	case "\x1b[B", "j":
+		t.move(1)
+	case "s":
+		t.act("start", reports)
+	case "x":
+		t.confirm("stop")
+	case "r":
+		t.confirm("redeploy")
+	case "+":
+		t.act("scale up", reports)
+	case "-":
+		t.act("scale down", reports)
+	}
+	return true
+}
+
+// move moves the selection by delta services
+func (t *top) move(delta int) {
+	if len(t.services) == 0 {
+		return
+	}
+	cursor := t.cursor + delta
+	if cursor < 0 || cursor >= len(t.services) {
+		return
+	}
+	t.cursor = cursor
+	t.follow(t.services[cursor].selected)
+}
+
+// follow makes s the selected service: its events are followed and its logs
+// are fetched at the next refresh
+func (t *top) follow(s selected) {
+	if s == t.current && t.eventsDone != nil {
+		return
+	}
+	if t.eventsDone != nil {
+		close(t.eventsDone)
+		t.eventsDone, t.eventCh = nil, nil
+	}
+
+	t.current = s
+	t.events, t.logs = nil, nil
+	if s.service == "" {
+		return
+	}
+
+	t.stale = true
+	t.eventCh = make(chan *internal.Event)
+	t.eventsDone = make(chan struct{})
+	go followEvents(s.app, s.service, t.eventCh, t.eventsDone)
+}
+
+func (t *top) confirm(action string) {
+	if t.current.service == "" {
+		return
+	}
+	t.pending = action
+	t.status = fmt.Sprintf("%s %s? [y/N]", strings.Title(action), t.current)
+}
+
+// act runs an action on the selected service in the background. Its progress
+// and warnings are sent on reports, nothing is printed while the dashboard is
+// displayed.
+func (t *top) act(action string, reports chan<- string) {
+	s := t.current
+	if s.service == "" {
+		return
+	}
+
+	number := -1
+	for _, service := range t.services {
+		if service.selected == s {
+			number = service.ContainerNumber
+		}
+	}
+	switch action {
+	case "scale up":
+		number++
+	case "scale down":
+		if number <= 0 {
+			t.status = fmt.Sprintf("%s has no container to scale down", s)
+			return
+		}
+		number--
+	}
+
+	t.status = fmt.Sprintf("%s: %s...", s, action)
+	go func() {
+		// The last warning is repeated when done, not to be overwritten
+		var warning string
+		report := func(message string) {
+			if strings.HasPrefix(message, "Warning: ") {
+				warning = message
+			}
+			reports <- fmt.Sprintf("%s: %s", s, message)
+		}
+
+		var err error
+		switch action {
+		case "start":
+			_, err = startService(s.app, s.service, report)
+		case "stop":
+			err = stopService(s.app, s.service, report)
+		case "redeploy":
+			var body []byte
+			body, err = json.Marshal(ServiceSpec{Application: s.app, Service: s.service}.Redeploy())
+			if err == nil {
+				path := fmt.Sprintf("/applications/%s/services/%s/redeploy", s.app, s.service)
+				_, err = redeployService(path, body, s.app, s.service, "redeploy", report)
+			}
+		default:
+			_, err = scaleService(s.app, s.service, number, false, report)
+		}
+
+		if err != nil {
+			report(fmt.Sprintf("%s failed: %s", action, err))
+		} else if warning != "" {
+			report(fmt.Sprintf("%s done. %s", action, warning))
+		} else {
+			report(fmt.Sprintf("%s done", action))
+		}
+	}()
+}
+
+// refresh fetches the services and the logs of the selected service in the
+// background, unless a refresh is already running
+func (t *top) refresh(refreshed chan<- topRefresh) {
+	if t.refreshing {
+		return
+	}
+	t.refreshing, t.stale = true, false
+
+	go func(apps []string, current selected) {
+		result := topRefresh{selected: current}
+		result.services, result.err = fetchTopServices(apps)
+		if result.err == nil && current.service != "" {
+			result.logs, result.err = fetchTopLogs(current)
+		}
+		refreshed <- result
+	}(t.apps, t.current)
+}
+
+// apply updates the dashboard with a refresh, keeping the selected service
+func (t *top) apply(result topRefresh) {
+	t.err = result.err
+	if result.err != nil {
+		return
+	}
+
+	t.services = result.services
+	t.updated = time.Now()
+	if result.selected == t.current {
+		t.logs = result.logs
+	}
+
+	t.cursor = 0
+	for i, service := range t.services {
+		if service.selected == t.current {
+			t.cursor = i
+		}
+	}
+	if len(t.services) > 0 {
+		t.follow(t.services[t.cursor].selected)
+	} else {
+		t.follow(selected{})
+	}
+}
+
+func fetchTopServices(apps []string) ([]topService, error) {
+	var services []topService
+
+	for _, app := range apps {
+		var names []string
+		b, err := fetch(fmt.Sprintf("/applications/%s/services", app))
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &names); err != nil {
+			return nil, err
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			service := topService{selected: selected{app, name}}
+			b, err := fetch(fmt.Sprintf("/applications/%s/services/%s", app, name))
+			if err != nil {
+				// The service may have been deleted meanwhile
+				continue
+			}
+			if err := json.Unmarshal(b, &service); err != nil {
+				return nil, err
+			}
+			services = append(services, service)
+		}
+	}
+
+	return services, nil
+}
+
+func fetchTopLogs(s selected) ([]string, error) {
+	var logs [][]string
+	b, err := fetch(fmt.Sprintf("/applications/%s/services/%s/logs?tail=%d", s.app, s.service, topLogLines))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &logs); err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0, len(logs))
+	for _, log := range logs {
+		lines = append(lines, strings.Join(log, " "))
+	}
+	return lines, nil
+}
+
+// DescribeEvent returns a one line description of an event, without its time
+func DescribeEvent(ev *internal.Event) string {
+	name := ev.ID
+	if name == "" {
+		name = ev.Service
+	}
+
+	line := fmt.Sprintf("%s %s", name, ev.Event)
+	if ev.PrevState != "" {
+		line += fmt.Sprintf(" %s -> %s", strings.ToUpper(ev.PrevState), strings.ToUpper(ev.State))
+	} else if ev.State != "" {
+		line += " " + strings.ToUpper(ev.State)
+	}
+	if ev.Data != nil && ev.Data.LastExitStatus != nil && ev.Data.LastExitStatus.ExitStatus != nil {
+		line += fmt.Sprintf(" (exit status %d)", *ev.Data.LastExitStatus.ExitStatus)
+	}
+	if ev.Message != "" {
+		line += ": " + ev.Message
+	}
+	return line
+}
+
+// eventTime returns the time of an event, or now if it has none
+func eventTime(ev *internal.Event) time.Time {
+	if ev.Timestamp <= 0 {
+		return time.Now()
+	}
+	return time.Unix(0, int64(ev.Timestamp*1e9))
+}
+
+// render draws the dashboard on the whole terminal
+func (t *top) render() {
+	// Some terminals, such as those of script(1), report a size of 0
+	width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width < 1 || height < 2 {
+		width, height = 80, 24
+	}
+
+	header := fmt.Sprintf("sail top - %s - %d service(s)", strings.Join(t.apps, ", "), len(t.services))
+	if !t.updated.IsZero() {
+		header += " - updated " + t.updated.Format("15:04:05")
+	}
+	lines := []string{header}
+	if t.err != nil {
+		lines = append(lines, fmt.Sprintf("Error: %s", t.err))
+	}
+	lines = append(lines, "")
+
+	// Services take at most half of the screen, scrolled to the selected one
+	rows := t.table()
+	visible := height/2 - len(lines) - 1
+	if visible < 1 {
+		visible = 1
+	}
+	first := 0
+	if t.cursor >= visible {
+		first = t.cursor - visible + 1
+	}
+	lines = append(lines, rows[0])
+	for i := first; i < len(t.services) && i < first+visible; i++ {
+		row := truncate(rows[i+1], width)
+		if i == t.cursor {
+			row = "\x1b[7m" + row + "\x1b[0m"
+		}
+		lines = append(lines, row)
+	}
+
+	if t.current.service != "" {
+		lines = append(lines, "", fmt.Sprintf("EVENTS %s", t.current))
+		lines = append(lines, t.events...)
+		lines = append(lines, "", fmt.Sprintf("LOGS %s", t.current))
+
+		logs := t.logs
+		if room := height - len(lines) - 1; room < len(logs) {
+			if room < 0 {
+				room = 0
+			}
+			logs = logs[len(logs)-room:]
+		}
+		lines = append(lines, logs...)
+	}
+
+	if len(lines) > height-1 {
+		lines = lines[:height-1]
+	}
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+
+	status := t.status
+	if status == "" {
+		status = "up/down select  s start  x stop  r redeploy  +/- scale  q quit"
+	}
+	lines = append(lines, status)
+
+	var screen bytes.Buffer
+	screen.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			screen.WriteString("\r\n")
+		}
+		if !strings.HasPrefix(line, "\x1b[7m") {
+			line = truncate(line, width)
+		}
+		screen.WriteString(line)
+		screen.WriteString("\x1b[K")
+	}
+	screen.WriteString("\x1b[J")
+	os.Stdout.Write(screen.Bytes())
+}
+
+// table returns the header and the rows of the service table, aligned
+func (t *top) table() []string {
+	var buffer bytes.Buffer
+	w := tabwriter.NewWriter(&buffer, 0, 4, 2, ' ', 0)
+	titles := []string{"NAME", "STATE", "CONTAINERS", "IPS"}
+	fmt.Fprintln(w, strings.Join(titles, "\t"))
+
+	for _, service := range t.services {
+		fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\n",
+			service.selected,
+			strings.ToUpper(service.State),
+			service.running(),
+			service.ContainerNumber,
+			strings.Join(service.ips(), ","))
+	}
+	w.Flush()
+
+	return strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+}
+
+// truncate cuts a line to width characters, tabs and control characters are
+// replaced not to break the screen
+func truncate(line string, width int) string {
+	runes := []rune(strings.Map(func(r rune) rune {
+		if r < ' ' {
+			return ' '
+		}
+		return r
+	}, line))
+	if len(runes) > width {
+		runes = runes[:width]
+	}
+	return string(runes)
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/runabove/sail/internal"
+)
+
+var restartPolicyRegexp = regexp.MustCompile(`^(no|always(:[0-9]+)?|on-failure(:[0-9]+)?)$`)
+
+// validationErrors collects every problem found in a service definition so
+// that they can all be reported at once
+type validationErrors []string
+
+func (e validationErrors) Error() string {
+	return "Invalid service definition:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// add appends err to the list. err may itself be a validationErrors.
+func (e *validationErrors) add(err error) {
+	if err == nil {
+		return
+	}
+	if errs, ok := err.(validationErrors); ok {
+		*e = append(*e, errs...)
+		return
+	}
+	*e = append(*e, err.Error())
+}
+
+// err returns nil when no problem was found
+func (e validationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate checks the semantics of a service definition before it is
+// submitted. Link targets existence is checked against the services endpoint.
+func (s ServiceSpec) Validate() error {
+	var errs validationErrors
+
+	errs.add(s.validateLocal())
+	errs.add(s.validateLinks())
+
+	return errs.err()
+}
+
+// validateLocal runs all checks that do not need an API round-trip
+func (s ServiceSpec) validateLocal() error {
+	var errs validationErrors
+
+	// Restart policy. Empty means unchanged on redeploy
+	if s.RestartPolicy != "" && !restartPolicyRegexp.MatchString(s.RestartPolicy) {
+		errs = append(errs, fmt.Sprintf("Invalid restart policy '%s': should be one of no, always[:<max>], on-failure[:<max>]", s.RestartPolicy))
+	}
+
+	if s.ContainerNumber < 0 {
+		errs = append(errs, fmt.Sprintf("Invalid container number %d: should not be negative", s.ContainerNumber))
+	}
+
+	// Volumes
+	for _, path := range sortedVolumes(s.Volumes) {
+		if !strings.HasPrefix(path, "/") {
+			errs = append(errs, fmt.Sprintf("Invalid volume '%s': path should be absolute", path))
+		}
+		if size, err := strconv.Atoi(s.Volumes[path].Size); err != nil || size < 1 {
+			errs = append(errs, fmt.Sprintf("Invalid volume '%s': size '%s' should be a positive number of GB", path, s.Volumes[path].Size))
+		}
+	}
+
+	// Environment
+	for _, env := range s.ContainerEnvironment {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || strings.ContainsAny(kv[0], " \t\n") {
+			errs = append(errs, fmt.Sprintf("Invalid environment variable '%s': should be of form KEY=val", env))
+		}
+	}
+
+	// Whitelisted CIDRs
+	for _, key := range sortedPorts(s.ContainerPorts) {
+		for _, pc := range s.ContainerPorts[key] {
+			for _, cidr := range pc.WhitelistedCidrs {
+				if err := checkCidr(cidr); err != nil {
+					errs = append(errs, err.Error())
+				}
+			}
+		}
+	}
+
+	errs.add(checkPortCollisions(s.ContainerPorts))
+
+	return errs.err()
+}
+
+// validateLinks checks that every link points to an existing service of the application
+func (s ServiceSpec) validateLinks() error {
+	if len(s.Links) == 0 {
+		return nil
+	}
+
+	var errs validationErrors
+
+	targets := make([]string, 0, len(s.Links))
+	for target := range s.Links {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	for _, target := range targets {
+		if target == s.Service {
+			errs = append(errs, fmt.Sprintf("Invalid link '%s:%s': a service cannot link to itself", target, s.Links[target]))
+		}
+	}
+
+	var services []string
+	if err := internal.GetJSON(fmt.Sprintf("/applications/%s/services", s.Application), &services); err != nil {
+		errs = append(errs, fmt.Sprintf("Could not check links: %s", err))
+		return errs.err()
+	}
+
+	existing := make(map[string]bool)
+	for _, service := range services {
+		existing[service] = true
+	}
+
+	for _, target := range targets {
+		if !existing[target] && target != s.Service {
+			errs = append(errs, fmt.Sprintf("Invalid link '%s:%s': service %s/%s does not exist", target, s.Links[target], s.Application, target))
+		}
+	}
+
+	return errs.err()
+}
+
+// checkCidr validates ip[/mask]
+func checkCidr(cidr string) error {
+	if strings.Contains(cidr, "/") {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("Invalid allowed network '%s': not a valid CIDR", cidr)
+		}
+	} else if net.ParseIP(cidr) == nil {
+		return fmt.Errorf("Invalid allowed network '%s': not a valid IP address", cidr)
+	}
+	return nil
+}
+
+// checkPortCollisions reports published ports used twice on the same network.
+// A port published without network is published on all networks.
+func checkPortCollisions(ports map[string][]PortConfig) error {
+	type published struct {
+		container string
+		network   string
+	}
+
+	var errs validationErrors
+	seen := make(map[string][]published)
+
+	for _, key := range sortedPorts(ports) {
+		protocol := "tcp"
+		if split := strings.SplitN(key, "/", 2); len(split) == 2 {
+			protocol = split[1]
+		}
+
+		for _, pc := range ports[key] {
+			pub := fmt.Sprintf("%d/%s", pc.PublishedPort, protocol)
+			for _, other := range seen[pub] {
+				if other.network == pc.Network || other.network == "" || pc.Network == "" {
+					network := pc.Network
+					if network == "" {
+						network = other.network
+					}
+					if network == "" {
+						network = "all networks"
+					} else {
+						network = "network " + network
+					}
+					errs = append(errs, fmt.Sprintf("Port collision: published port %s on %s is used by both %s and %s", pub, network, other.container, key))
+				}
+			}
+			seen[pub] = append(seen[pub], published{container: key, network: pc.Network})
+		}
+	}
+
+	return errs.err()
+}
+
+func sortedPorts(ports map[string][]PortConfig) []string {
+	keys := make([]string, 0, len(ports))
+	for key := range ports {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedVolumes(volumes map[string]VolumeConfig) []string {
+	keys := make([]string, 0, len(volumes))
+	for key := range volumes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/runabove/sail/internal"
+)
+
+// Exit statuses of wait commands
+const (
+	ExitWaitTimeout    = 3
+	ExitWaitUnexpected = 4
+)
+
+// ErrWaitTimeout is returned by WaitState when the timeout expires
+var ErrWaitTimeout = errors.New("timeout")
+
+// UnexpectedStateError is returned when a wait ends in a state from which
+// the wanted state will not be reached
+type UnexpectedStateError struct {
+	ID     string
+	State  string
+	Reason string
+}
+
+func (e *UnexpectedStateError) Error() string {
+	return fmt.Sprintf("%s reached state %s: %s", e.ID, e.State, e.Reason)
+}
+
+// waitPollInterval is the delay between 2 checks of the state of a service.
+// Events trigger a check as soon as they arrive.
+const waitPollInterval = 2 * time.Second
+
+var (
+	waitState   string
+	waitTimeout time.Duration
+)
+
+func waitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait until a service reaches a state: sail service wait [<applicationName>/]<serviceId> [--state running|stopped] [--timeout 5m]",
+		Long: `Wait until a service reaches a state: sail service wait [<applicationName>/]<serviceId> [--state running|stopped] [--timeout 5m]
+
+Blocks until the service and all its containers are in the wanted state and
+prints the state transitions. The service event stream is followed and the
+state is polled as well, in case the stream drops.
+
+Exit status is 0 when the state is reached, 3 on timeout and 4 when a
+container exits while waiting for 'running' or the service is deleted.
+	"example: sail service wait my-app/web --state running --timeout 2m"
+`,
+		Run: cmdWait,
+	}
+
+	BindWaitFlags(cmd)
+
+	return cmd
+}
+
+// BindWaitFlags registers --state and --timeout on a wait command
+func BindWaitFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&waitState, "state", "running", "state to wait for, 'running' or 'stopped'")
+	cmd.Flags().DurationVar(&waitTimeout, "timeout", 0, "maximum time to wait, no timeout by default")
+}
+
+// WaitFlags returns the values of the flags registered with BindWaitFlags
+func WaitFlags() (string, time.Duration) {
+	state := strings.ToLower(waitState)
+	if state != "running" && state != "stopped" {
+		internal.Exit("Error: Invalid state '%s'. Must be one of 'running' and 'stopped'\n", waitState)
+	}
+	return state, waitTimeout
+}
+
+func cmdWait(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Invalid usage. sail service wait [<applicationName>/]<serviceId>. Please see sail service wait --help")
+		os.Exit(1)
+	}
+
+	host, app, service, _, err := internal.ParseResourceName(args[0])
+	internal.Check(err)
+
+	if !internal.CheckHostConsistent(host) {
+		fmt.Fprintf(os.Stderr, "Error: Invalid Host %s for endpoint %s\n", host, internal.Host)
+		os.Exit(1)
+	}
+
+	state, timeout := WaitFlags()
+	err = WaitState(app, service, state, timeout, func(ev *internal.Event) error {
+		PrintTransition(ev)
+		return UnexpectedTransition(ev, state)
+	})
+	ExitWait(fmt.Sprintf("%s/%s", app, service), state, timeout, err)
+}
+
+// PrintTransition prints a state transition
+func PrintTransition(ev *internal.Event) {
+	name := ev.ID
+	if name == "" {
+		name = ev.Service
+	}
+
+	if ev.PrevState == "" {
+		fmt.Printf("%s %s: %s\n", time.Now().Format("15:04:05"), name, strings.ToUpper(ev.State))
+	} else {
+		fmt.Printf("%s %s: %s -> %s\n", time.Now().Format("15:04:05"), name, strings.ToUpper(ev.PrevState), strings.ToUpper(ev.State))
+	}
+}
+
+// UnexpectedTransition returns an UnexpectedStateError when a container exits
+// while waiting for state 'running'
+func UnexpectedTransition(ev *internal.Event, state string) error {
+	if state == "running" && ev.Data != nil && ev.Data.LastExitStatus != nil {
+		reason := ev.Data.LastExitStatus.Reason
+		if status := ev.Data.LastExitStatus.ExitStatus; status != nil {
+			reason = fmt.Sprintf("%s with status %d", reason, *status)
+		}
+		return &UnexpectedStateError{ID: ev.ID, State: strings.ToUpper(ev.State), Reason: reason}
+	}
+	return nil
+}
+
+// ExitWait exits with the status matching the result of a wait
+func ExitWait(name, state string, timeout time.Duration, err error) {
+	switch e := err.(type) {
+	case nil:
+		fmt.Fprintf(os.Stderr, "%s is %s\n", name, state)
+		os.Exit(0)
+	case *UnexpectedStateError:
+		fmt.Fprintf(os.Stderr, "Error: %s\n", e)
+		os.Exit(ExitWaitUnexpected)
+	case *internal.Error:
+		fmt.Fprintf(os.Stderr, "Error: %s\n", e)
+		if e.Code == http.StatusNotFound {
+			os.Exit(ExitWaitUnexpected)
+		}
+		os.Exit(1)
+	}
+
+	if err == ErrWaitTimeout {
+		fmt.Fprintf(os.Stderr, "Error: %s is not %s after %s\n", name, state, timeout)
+		os.Exit(ExitWaitTimeout)
+	}
+	internal.Check(err)
+}
+
+// serviceStatus is the part of a service definition describing its containers
+type serviceStatus struct {
+	State           string `json:"state"`
+	ContainerNumber int    `json:"container_number"`
+	Containers      map[string]struct {
+		State string `json:"state"`
+	} `json:"containers"`
+}
+
+// reached tells whether the service and all its containers are in state
+func (s serviceStatus) reached(state string) bool {
+	if !strings.EqualFold(s.State, state) {
+		return false
+	}
+
+	// Stopped services may have no container left
+	if len(s.Containers) == 0 {
+		return s.ContainerNumber == 0 || !strings.EqualFold(state, "running")
+	}
+
+	for _, container := range s.Containers {
+		if !strings.EqualFold(container.State, state) {
+			return false
+		}
+	}
+	return len(s.Containers) >= s.ContainerNumber
+}
+
+func fetchServiceStatus(app, service string) (serviceStatus, error) {
+	var status serviceStatus
+
+	b, err := fetch(fmt.Sprintf("/applications/%s/services/%s", app, service))
+	if err != nil {
+		return status, err
+	}
+
+	err = json.Unmarshal(b, &status)
+	return status, err
+}
+
+// fetch GETs path and returns an error instead of exiting when it fails
+func fetch(path string) ([]byte, error) {
+	b, code, err := internal.Request("GET", path, nil)
+	if err != nil {
+		return nil, err
+	} else if code != http.StatusOK {
+		if e := internal.DecodeError(b); e != nil {
+			return nil, e
+		}
+		return nil, fmt.Errorf("unexpected status code %d", code)
+	}
+	return b, nil
+}
+
+// StateTracker reports each state change once, whether it was received on the
+// event stream or found by polling
+type StateTracker struct {
+	states map[string]string
+	report func(*internal.Event) error
+}
+
+// NewStateTracker returns a tracker passing each state change to report, which
+// may be nil
+func NewStateTracker(report func(*internal.Event) error) *StateTracker {
+	return &StateTracker{states: make(map[string]string), report: report}
+}
+
+// Event reports a state change received on the event stream
+func (t *StateTracker) Event(ev *internal.Event) error {
+	if ev.State == "" || strings.EqualFold(t.states[ev.ID], ev.State) && ev.Data == nil {
+		return nil
+	}
+	if ev.PrevState == "" {
+		ev.PrevState = t.states[ev.ID]
+	}
+	t.states[ev.ID] = ev.State
+	if t.report == nil {
+		return nil
+	}
+	return t.report(ev)
+}
+
+// Poll reports a state change found by polling
+func (t *StateTracker) Poll(service, id, state string) error {
+	if state == "" || strings.EqualFold(t.states[id], state) {
+		return nil
+	}
+	return t.Event(&internal.Event{Service: service, ID: id, State: state, PrevState: t.states[id]})
+}
+
+// WaitState waits until a service and all its containers are in state. Service
+// events are followed to react quickly and the state is also polled, in case the
+// event stream is not available. onEvent, if not nil, is called for each state
+// transition and the wait stops if it returns an error.
+// Returns ErrWaitTimeout when timeout expires, 0 means no timeout.
+func WaitState(app, service, state string, timeout time.Duration, onEvent func(*internal.Event) error) error {
+	tracker := NewStateTracker(onEvent)
+
+	return WaitFor(app, service, timeout, tracker.Event, func() (bool, error) {
+		status, err := fetchServiceStatus(app, service)
+		if err != nil {
+			return false, err
+		}
+		for id, container := range status.Containers {
+			if err := tracker.Poll(service, id, container.State); err != nil {
+				return false, err
+			}
+		}
+		return status.reached(state), nil
+	})
+}
+
+// WaitFor calls check until it reports success, an error or timeout expires.
+// check is called every few seconds and after each event of the service,
+// which are passed to onEvent first. Events are not followed if service is empty.
+func WaitFor(app, service string, timeout time.Duration, onEvent func(*internal.Event) error, check func() (bool, error)) error {
+	events := make(chan *internal.Event)
+	done := make(chan struct{})
+	defer close(done)
+
+	if service != "" {
+		go followEvents(app, service, events, done)
+	}
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		deadline = time.After(timeout)
+	}
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := check()
+		if err != nil || ok {
+			return err
+		}
+
+		select {
+		case ev := <-events:
+			if err := onEvent(ev); err != nil {
+				return err
+			}
+		case <-ticker.C:
+		case <-deadline:
+			return ErrWaitTimeout
+		}
+	}
+}
+
+// followEvents sends the events of a service on events until done is closed.
+// It gives up silently if the stream can not be opened or closes.
+func followEvents(app, service string, events chan<- *internal.Event, done <-chan struct{}) {
+	buffer, code, err := internal.Stream("GET", fmt.Sprintf("/applications/%s/services/%s/events", app, service), nil)
+	if err != nil {
+		return
+	}
+	defer buffer.Close()
+	if code != http.StatusOK {
+		return
+	}
+
+	go func() {
+		<-done
+		buffer.Close()
+	}()
+
+	scanner := bufio.NewScanner(buffer)
+	for scanner.Scan() {
+		ev := internal.DecodeEvent(scanner.Bytes())
+		if ev == nil || ev.Event == "" {
+			continue
+		}
+		select {
+		case events <- ev:
+		case <-done:
+			return
+		}
+	}
+}
+
+// State returns the current state of a service, in lower case
+func State(app, service string) string {
+	status, err := fetchServiceStatus(app, service)
+	internal.Check(err)
+	return strings.ToLower(status.State)
+}